@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/deeragoo/deecli/internal/agent"
+)
+
+// newAgentCmd builds the "agent" command, which caches unlocked tokens so
+// consecutive commands do not prompt for the passphrase again.
+func newAgentCmd() *cobra.Command {
+	agentCmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Start a background agent that caches unlocked tokens",
+		Long: "Start a background agent, in the spirit of ssh-agent, that holds unlocked tokens and\n" +
+			"the vault key in memory for --ttl. Commands that decrypt a token ask the agent before\n" +
+			"prompting for a passphrase. The socket is $" + agent.SocketEnv + " or ~/.deecli/agent.sock.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			ttl, _ := cmd.Flags().GetDuration("ttl")
+			foreground, _ := cmd.Flags().GetBool("foreground")
+
+			if status, err := agent.GetStatus(); err == nil {
+				fmt.Printf("Agent already running (pid %d) on %s\n", status.PID, status.Socket)
+				return
+			}
+
+			if foreground {
+				if err := runAgent(ttl); err != nil {
+					fmt.Println("Error running agent:", err)
+					os.Exit(1)
+				}
+				return
+			}
+
+			exe, err := os.Executable()
+			if err != nil {
+				fmt.Println("Error locating deecli binary:", err)
+				return
+			}
+
+			child := exec.Command(exe, "agent", "--foreground", "--ttl", ttl.String())
+			if err := child.Start(); err != nil {
+				fmt.Println("Error starting agent:", err)
+				return
+			}
+			if err := child.Process.Release(); err != nil {
+				fmt.Println("Warning: failed to release agent process:", err)
+			}
+
+			// Wait for the socket to come up so the next command can use it
+			deadline := time.Now().Add(3 * time.Second)
+			for time.Now().Before(deadline) {
+				if status, err := agent.GetStatus(); err == nil {
+					fmt.Printf("Agent started (pid %d) on %s, caching unlocked tokens for %s\n", status.PID, status.Socket, status.TTL)
+					return
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			fmt.Println("Error: agent did not start; run 'deecli agent --foreground' to see why")
+		},
+	}
+	agentCmd.Flags().Duration("ttl", agent.DefaultTTL, "How long unlocked tokens stay cached")
+	agentCmd.Flags().Bool("foreground", false, "Run the agent in the foreground instead of detaching")
+
+	// agent lock command
+	lockCmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Forget every token cached by the agent",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := agent.Lock(); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Println("Agent locked; cached tokens wiped.")
+		},
+	}
+
+	// agent status command
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show whether the agent is running and how many items it holds",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			status, err := agent.GetStatus()
+			if err != nil {
+				fmt.Println("Agent not running.")
+				return
+			}
+			fmt.Printf("Agent running (pid %d)\n", status.PID)
+			fmt.Printf("  Socket:  %s\n", status.Socket)
+			fmt.Printf("  Started: %s\n", status.Started.Format(time.RFC3339))
+			fmt.Printf("  TTL:     %s\n", status.TTL)
+			fmt.Printf("  Cached:  %d item(s)\n", status.Items)
+		},
+	}
+
+	// agent stop command
+	stopCmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Wipe the cache and stop the agent",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			status, err := agent.Stop()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Printf("Agent (pid %d) stopped.\n", status.PID)
+		},
+	}
+
+	agentCmd.AddCommand(lockCmd, statusCmd, stopCmd)
+	return agentCmd
+}
+
+// runAgent serves the agent socket until it is stopped or signalled.
+func runAgent(ttl time.Duration) error {
+	server := agent.NewServer(agent.SocketPath(), ttl)
+	if err := server.Listen(); err != nil {
+		return err
+	}
+
+	// Outlive the terminal that started us, but clean up on termination
+	signal.Ignore(syscall.SIGHUP)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		if err := server.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, "Warning: failed to stop agent:", err)
+		}
+	}()
+
+	return server.Serve()
+}
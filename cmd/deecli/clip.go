@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/deeragoo/deecli/internal/clipboard"
+)
+
+// defaultClearAfter is how long a copied token stays on the clipboard.
+const defaultClearAfter = 45 * time.Second
+
+// copyToClipboard puts value on the clipboard and, unless clearAfter is zero,
+// leaves a detached deecli process behind that clears it again. Only the
+// digest of value is handed to that process.
+func copyToClipboard(value string, clearAfter time.Duration) error {
+	clip, err := clipboard.Default()
+	if err != nil {
+		return err
+	}
+	if err := clip.Copy(value); err != nil {
+		return err
+	}
+	if clearAfter <= 0 {
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("token copied, but it will not be cleared: %w", err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("token copied, but it will not be cleared: %w", err)
+	}
+	child := exec.Command(exe, "clipboard-clear", "--after", clearAfter.String())
+	child.Stdin = r
+	err = child.Start()
+	if cerr := r.Close(); cerr != nil {
+		fmt.Println("Warning: failed to close pipe:", cerr)
+	}
+	if err != nil {
+		if cerr := w.Close(); cerr != nil {
+			fmt.Println("Warning: failed to close pipe:", cerr)
+		}
+		return fmt.Errorf("token copied, but it will not be cleared: %w", err)
+	}
+	_, err = fmt.Fprintln(w, clipboard.Digest(value))
+	if cerr := w.Close(); cerr != nil {
+		fmt.Println("Warning: failed to close pipe:", cerr)
+	}
+	if err != nil {
+		return fmt.Errorf("token copied, but it will not be cleared: %w", err)
+	}
+	if err := child.Process.Release(); err != nil {
+		fmt.Println("Warning: failed to release clipboard process:", err)
+	}
+	return nil
+}
+
+// newClipboardClearCmd builds the hidden command copyToClipboard starts. It
+// reads a digest from stdin, waits, and clears the clipboard if it still
+// holds the value with that digest.
+func newClipboardClearCmd() *cobra.Command {
+	clearCmd := &cobra.Command{
+		Use:    "clipboard-clear",
+		Short:  "Clear the clipboard later if it still holds a copied token",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			after, _ := cmd.Flags().GetDuration("after")
+
+			digest, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			digest = strings.TrimSpace(digest)
+			if err != nil || digest == "" {
+				fmt.Fprintln(os.Stderr, "Error: expected a digest on stdin")
+				os.Exit(1)
+			}
+			// Outlive the terminal that started us
+			signal.Ignore(syscall.SIGHUP)
+
+			clip, err := clipboard.Default()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+			if _, err := clipboard.ClearAfter(clip, digest, after); err != nil {
+				fmt.Fprintln(os.Stderr, "Error clearing clipboard:", err)
+				os.Exit(1)
+			}
+		},
+	}
+	clearCmd.Flags().Duration("after", defaultClearAfter, "How long to wait before clearing")
+	return clearCmd
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/deeragoo/deecli/decryptonite"
+	"github.com/deeragoo/deecli/encryptonite"
+	"github.com/deeragoo/deecli/internal/dockercred"
+	"github.com/deeragoo/deecli/internal/store"
+	"github.com/deeragoo/deecli/version"
+)
+
+// dockerTag marks entries holding registry credentials.
+const dockerTag = "docker"
+
+// newDockerCredentialCmd builds the "docker-credential" command, which docker
+// also reaches by running deecli as docker-credential-deecli.
+func newDockerCredentialCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "docker-credential get|store|erase|list|version",
+		Short: "Docker credential helper keeping registry logins in ~/.secrets.json",
+		Long: "Docker credential helper keeping registry logins in ~/.secrets.json instead of\n" +
+			"~/.docker/config.json. Link deecli as " + dockercred.Program + " somewhere on PATH\n" +
+			"and set \"credsStore\": \"deecli\" in ~/.docker/config.json.\n\n" +
+			"Each registry is a token named " + dockercred.NamePrefix + "HOST, with the registry username kept\n" +
+			"beside it. Docker runs the helper without a terminal, so tokens are unlocked through the\n" +
+			"agent or DEECLI_PASSPHRASE_CMD.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			switch args[0] {
+			case "get":
+				err = dockerCredentialGet()
+			case "store":
+				err = dockerCredentialStore()
+			case "erase":
+				err = dockerCredentialErase()
+			case "list":
+				err = dockerCredentialList()
+			case "version":
+				fmt.Println(dockercred.Program, version.Version)
+			default:
+				err = fmt.Errorf("unknown credential action %q", args[0])
+			}
+			// Docker shows whatever the helper printed as the error message
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+// dockerCredentialArgs returns the command line to run when deecli is invoked
+// as docker-credential-deecli, and false otherwise.
+func dockerCredentialArgs() ([]string, bool) {
+	if strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe") != dockercred.Program {
+		return nil, false
+	}
+	return append([]string{"docker-credential"}, os.Args[1:]...), true
+}
+
+func dockerCredentialGet() error {
+	serverURL, err := dockercred.ReadServerURL(os.Stdin)
+	if err != nil {
+		return err
+	}
+	secrets, err := store.OpenDefault()
+	if err != nil {
+		return err
+	}
+	name := dockercred.TokenName(serverURL)
+	entry, ok := secrets.Entry(name)
+	if !ok {
+		return dockercred.ErrNotFound
+	}
+	secret, err := decryptonite.GetTokenByName(name)
+	if err != nil {
+		return fmt.Errorf("error decrypting %q: %w", name, err)
+	}
+	return json.NewEncoder(os.Stdout).Encode(dockercred.Credentials{
+		ServerURL: serverURL,
+		Username:  registryUsername(entry),
+		Secret:    secret,
+	})
+}
+
+// dockerCredentialStore saves the credentials of a 'docker login'. Logging in
+// again with the same credentials leaves the token and its history alone.
+func dockerCredentialStore() error {
+	var c dockercred.Credentials
+	if err := json.NewDecoder(os.Stdin).Decode(&c); err != nil {
+		return fmt.Errorf("error decoding credentials: %w", err)
+	}
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	secrets, err := store.OpenDefault()
+	if err != nil {
+		return err
+	}
+	name := dockercred.TokenName(c.ServerURL)
+	entry, exists := secrets.Entry(name)
+	if exists && registryUsername(entry) == c.Username {
+		current, err := decryptonite.GetTokenByName(name)
+		if err != nil {
+			return fmt.Errorf("error decrypting %q: %w", name, err)
+		}
+		if current == c.Secret {
+			return nil
+		}
+	}
+
+	opts := encryptonite.EncryptOptions{
+		Name:     name,
+		Value:    c.Secret,
+		Force:    true,
+		Service:  c.ServerURL,
+		Username: c.Username,
+		Status:   os.Stderr,
+	}
+	if !exists {
+		opts.Tags = []string{dockerTag}
+	}
+	return encryptonite.EncryptToken(opts)
+}
+
+// dockerCredentialErase deletes the token of a registry on 'docker logout'.
+func dockerCredentialErase() error {
+	serverURL, err := dockercred.ReadServerURL(os.Stdin)
+	if err != nil {
+		return err
+	}
+	secrets, err := store.OpenDefault()
+	if err != nil {
+		return err
+	}
+	if !secrets.Delete(dockercred.TokenName(serverURL)) {
+		return dockercred.ErrNotFound
+	}
+	if err := encryptonite.SignIndex(secrets); err != nil {
+		return err
+	}
+	return secrets.Save()
+}
+
+// dockerCredentialList prints the server URL and username of every stored
+// registry login; it reads metadata only, so nothing is unlocked.
+func dockerCredentialList() error {
+	secrets, err := store.OpenDefault()
+	if err != nil {
+		return err
+	}
+	logins := map[string]string{}
+	for _, name := range secrets.List() {
+		if !strings.HasPrefix(name, dockercred.NamePrefix) {
+			continue
+		}
+		entry, _ := secrets.Entry(name)
+		serverURL := entry.Service
+		if serverURL == "" {
+			serverURL = strings.TrimPrefix(name, dockercred.NamePrefix)
+		}
+		logins[serverURL] = registryUsername(entry)
+	}
+	return json.NewEncoder(os.Stdout).Encode(logins)
+}
+
+// registryUsername returns the username of a registry login. Logins stored
+// before entries had a username field kept it as the owner.
+func registryUsername(entry store.Entry) string {
+	if entry.Username != "" {
+		return entry.Username
+	}
+	return entry.Owner
+}
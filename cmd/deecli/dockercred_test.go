@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/deeragoo/deecli/internal/store"
+)
+
+func TestRegistryUsername(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry store.Entry
+		want  string
+	}{
+		{"username field", store.Entry{Username: "octocat", Owner: "platform-team"}, "octocat"},
+		{"stored by an earlier release", store.Entry{Owner: "octocat"}, "octocat"},
+		{"neither", store.Entry{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := registryUsername(tt.entry); got != tt.want {
+				t.Errorf("registryUsername = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/deeragoo/deecli/decryptonite"
+)
+
+// envNamePattern matches portable environment variable names.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// forwardedSignals are relayed from deecli to the child process.
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// newExecCmd builds the "exec" command, which runs a program with decrypted
+// tokens injected into its environment only.
+func newExecCmd() *cobra.Command {
+	execCmd := &cobra.Command{
+		Use:   "exec --secret ENV=token [--secret ...] -- <command> [args...]",
+		Short: "Run a command with decrypted tokens set as environment variables",
+		Long: "Decrypt the named tokens from ~/.secrets.json and run <command> with each one set\n" +
+			"in its environment. Values are never printed and are not exported to the calling\n" +
+			"shell. Signals are forwarded to the command and its exit code is returned.",
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			mappings, _ := cmd.Flags().GetStringArray("secret")
+
+			env, err := secretEnv(mappings)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+
+			os.Exit(runWithEnv(args, env))
+		},
+	}
+	execCmd.Flags().StringArray("secret", nil, "Inject token as ENV=token-name (repeatable)")
+	// Everything after the command name belongs to the command
+	execCmd.Flags().SetInterspersed(false)
+
+	return execCmd
+}
+
+// secretEnv decrypts each ENV=token mapping and returns the child's
+// environment: the current one with those variables replaced.
+func secretEnv(mappings []string) ([]string, error) {
+	if len(mappings) == 0 {
+		return nil, errors.New("at least one --secret ENV=token is required")
+	}
+
+	values := map[string]string{}
+	for _, mapping := range mappings {
+		envName, tokenName, ok := strings.Cut(mapping, "=")
+		if !ok || tokenName == "" {
+			return nil, fmt.Errorf("invalid --secret %q, expected ENV=token", mapping)
+		}
+		if !envNamePattern.MatchString(envName) {
+			return nil, fmt.Errorf("invalid environment variable name %q", envName)
+		}
+
+		token, err := decryptonite.GetTokenByName(tokenName)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting %q: %w", tokenName, err)
+		}
+		values[envName] = token
+	}
+
+	env := make([]string, 0, len(os.Environ())+len(values))
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if _, overridden := values[name]; !overridden {
+			env = append(env, kv)
+		}
+	}
+	for name, value := range values {
+		env = append(env, name+"="+value)
+	}
+	return env, nil
+}
+
+// runWithEnv runs args with env, forwarding signals, and returns the exit
+// code to propagate: the child's own, or 128+signal if it was killed.
+func runWithEnv(args []string, env []string) int {
+	child := exec.Command(args[0], args[1:]...)
+	child.Env = env
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+
+	if err := child.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error starting command:", err)
+		return 127
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, forwardedSignals...)
+	defer signal.Stop(sigs)
+	go func() {
+		for sig := range sigs {
+			_ = child.Process.Signal(sig)
+		}
+	}()
+
+	err := child.Wait()
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		fmt.Fprintln(os.Stderr, "Error running command:", err)
+		return 1
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return exitErr.ExitCode()
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/deeragoo/deecli/decryptonite"
+	"github.com/deeragoo/deecli/encryptonite"
+	"github.com/deeragoo/deecli/internal/gitcred"
+	"github.com/deeragoo/deecli/internal/settings"
+	"github.com/deeragoo/deecli/internal/store"
+)
+
+// gitTag marks entries created by 'git-credential store'; only those are
+// deleted by 'git-credential erase'.
+const gitTag = "git-credential"
+
+// newGitCredentialCmd builds the "git-credential" command, a git credential
+// helper serving passwords from ~/.secrets.json, and the commands managing
+// which token answers for which host.
+func newGitCredentialCmd() *cobra.Command {
+	gitCmd := &cobra.Command{
+		Use:   "git-credential get|store|erase",
+		Short: "Git credential helper serving tokens from ~/.secrets.json",
+		Long: "Git credential helper serving tokens from ~/.secrets.json. Enable it with\n\n" +
+			"  git config --global credential.helper \"deecli git-credential\"\n\n" +
+			"Git asks for a host (and a path with credential.useHttpPath); the token is the one\n" +
+			"mapped with 'deecli git-credential map'. Git runs the helper without a terminal, so\n" +
+			"tokens are unlocked through the agent, --passphrase-file or DEECLI_PASSPHRASE_CMD.\n" +
+			"Credentials git stores for unmapped hosts are saved as git/[USER@]HOST and mapped.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			c, err := gitcred.Read(os.Stdin)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "deecli:", err)
+				os.Exit(1)
+			}
+
+			switch args[0] {
+			case "get":
+				err = gitCredentialGet(c)
+			case "store":
+				err = gitCredentialStore(c)
+			case "erase":
+				err = gitCredentialErase(c)
+			default:
+				// Git expects helpers to ignore actions they do not know
+				return
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "deecli:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	// git-credential map command
+	mapCmd := &cobra.Command{
+		Use:   "map HOST[/PATH] NAME",
+		Short: "Answer git's requests for HOST (or a repository path below it) with token NAME",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			m := gitMappingFromFlags(cmd, args[0])
+			m.Name = args[1]
+
+			s, err := settings.Load()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			s.GitCredentials = slices.DeleteFunc(s.GitCredentials, m.SameTarget)
+			s.GitCredentials = append(s.GitCredentials, m)
+			if err := s.Save(); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Printf("Git credentials for %s now come from %q.\n", gitTarget(m), m.Name)
+		},
+	}
+
+	// git-credential unmap command
+	unmapCmd := &cobra.Command{
+		Use:   "unmap HOST[/PATH]",
+		Short: "Remove a host mapping (the token itself is kept)",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			m := gitMappingFromFlags(cmd, args[0])
+
+			s, err := settings.Load()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			before := len(s.GitCredentials)
+			s.GitCredentials = slices.DeleteFunc(s.GitCredentials, m.SameTarget)
+			if len(s.GitCredentials) == before {
+				fmt.Printf("No mapping for %s.\n", gitTarget(m))
+				return
+			}
+			if err := s.Save(); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Printf("Removed the mapping for %s.\n", gitTarget(m))
+		},
+	}
+
+	for _, c := range []*cobra.Command{mapCmd, unmapCmd} {
+		c.Flags().String("username", "", "Username sent with the token; limits the mapping to this user")
+		c.Flags().String("protocol", "", "Limit the mapping to one protocol (e.g. https)")
+	}
+
+	// git-credential mappings command
+	mappingsCmd := &cobra.Command{
+		Use:   "mappings",
+		Short: "List which token answers for which host",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			s, err := settings.Load()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if len(s.GitCredentials) == 0 {
+				fmt.Println("No git credential mappings; add one with 'deecli git-credential map'.")
+				return
+			}
+			fmt.Printf("%-40s %s\n", "TARGET", "TOKEN")
+			for _, m := range s.GitCredentials {
+				fmt.Printf("%-40s %s\n", gitTarget(m), m.Name)
+			}
+		},
+	}
+
+	gitCmd.AddCommand(mapCmd, unmapCmd, mappingsCmd)
+	return gitCmd
+}
+
+// gitMappingFromFlags builds the target of a mapping from HOST[/PATH] and the
+// --username and --protocol flags.
+func gitMappingFromFlags(cmd *cobra.Command, target string) gitcred.Mapping {
+	var m gitcred.Mapping
+	m.Host, m.Path = gitcred.ParseTarget(target)
+	m.Username, _ = cmd.Flags().GetString("username")
+	m.Protocol, _ = cmd.Flags().GetString("protocol")
+	return m
+}
+
+// gitTarget formats the target of m as [PROTOCOL://][USER@]HOST[/PATH].
+func gitTarget(m gitcred.Mapping) string {
+	var b strings.Builder
+	if m.Protocol != "" {
+		b.WriteString(m.Protocol + "://")
+	}
+	if m.Username != "" {
+		b.WriteString(m.Username + "@")
+	}
+	b.WriteString(m.Host)
+	if m.Path != "" {
+		b.WriteString("/" + m.Path)
+	}
+	return b.String()
+}
+
+// gitCredentialGet answers git with the token mapped to c. Hosts without a
+// mapping or token get no answer, so git moves on to its next helper.
+func gitCredentialGet(c gitcred.Credential) error {
+	s, err := settings.Load()
+	if err != nil {
+		return err
+	}
+	m, ok := gitcred.Match(s.GitCredentials, c)
+	if !ok {
+		return nil
+	}
+	token, err := decryptonite.GetTokenByName(m.Name)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error decrypting %q: %w", m.Name, err)
+	}
+
+	answer := gitcred.Credential{Username: c.Username, Password: token}
+	if answer.Username == "" {
+		answer.Username = m.Username
+	}
+	return answer.Write(os.Stdout)
+}
+
+// gitCredentialStore saves a password git reports as working. Git does so
+// after every successful request, so an unchanged password is left alone
+// rather than adding a version to the token's history.
+func gitCredentialStore(c gitcred.Credential) error {
+	if c.Host == "" || c.Password == "" {
+		return nil
+	}
+	s, err := settings.Load()
+	if err != nil {
+		return err
+	}
+	m, mapped := gitcred.Match(s.GitCredentials, c)
+	if !mapped {
+		m = gitcred.Mapping{
+			Host:     strings.ToLower(c.Host),
+			Path:     strings.Trim(c.Path, "/"),
+			Protocol: c.Protocol,
+			Username: c.Username,
+			Name:     gitcred.DefaultName(c),
+		}
+	}
+
+	current, err := decryptonite.GetTokenByName(m.Name)
+	exists := !errors.Is(err, store.ErrNotFound)
+	if exists && err != nil {
+		return fmt.Errorf("error decrypting %q: %w", m.Name, err)
+	}
+	if exists && current == c.Password {
+		return nil
+	}
+
+	opts := encryptonite.EncryptOptions{Name: m.Name, Value: c.Password, Force: true, Status: os.Stderr}
+	if !exists {
+		opts.Tags = []string{gitTag}
+		opts.Service = c.Host
+	}
+	if err := encryptonite.EncryptToken(opts); err != nil {
+		return err
+	}
+	if mapped {
+		return nil
+	}
+	s.GitCredentials = append(s.GitCredentials, m)
+	return s.Save()
+}
+
+// gitCredentialErase deletes the token mapped to c after git reports it was
+// rejected, if 'git-credential store' created it. Git erases after a single
+// failed request, so tokens saved by hand, which may serve other purposes,
+// are never deleted. A token that has changed since git read it is kept, and
+// the mapping always is, so the next 'store' saves the new password under the
+// same name.
+func gitCredentialErase(c gitcred.Credential) error {
+	s, err := settings.Load()
+	if err != nil {
+		return err
+	}
+	m, ok := gitcred.Match(s.GitCredentials, c)
+	if !ok {
+		return nil
+	}
+	secrets, err := store.OpenDefault()
+	if err != nil {
+		return err
+	}
+	entry, ok := secrets.Entry(m.Name)
+	if !ok {
+		return nil
+	}
+	if !slices.Contains(entry.Tags, gitTag) {
+		fmt.Fprintf(os.Stderr, "deecli: git rejected %q; keeping it since it was not stored by git\n", m.Name)
+		return nil
+	}
+
+	current, err := decryptonite.GetTokenByName(m.Name)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error decrypting %q: %w", m.Name, err)
+	}
+	if c.Password != "" && c.Password != current {
+		return nil
+	}
+
+	if !secrets.Delete(m.Name) {
+		return nil
+	}
+	if err := encryptonite.SignIndex(secrets); err != nil {
+		return err
+	}
+	return secrets.Save()
+}
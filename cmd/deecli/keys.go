@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/deeragoo/deecli/encryptonite"
+	"github.com/deeragoo/deecli/internal/askpass"
+	"github.com/deeragoo/deecli/internal/factors"
+	"github.com/deeragoo/deecli/internal/keys"
+	"github.com/deeragoo/deecli/internal/policy"
+	"github.com/deeragoo/deecli/internal/settings"
+)
+
+// newKeysCmd builds the "keys" command group for the X25519 keypairs used by
+// 'deecli secrets share' and 'deecli secrets receive'.
+func newKeysCmd() *cobra.Command {
+	keysCmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage sharing keypairs and unlock keyfiles",
+	}
+
+	// keys generate command
+	generateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Create your keypair in ~/.deecli/identity",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			label, _ := cmd.Flags().GetString("label")
+
+			passphrase, err := askpass.ReadNew("Enter passphrase to protect your private key: ")
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if err := policy.Enforce(passphrase); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			public, err := keys.Generate(passphrase, label)
+			if err != nil {
+				fmt.Println("Error generating keypair:", err)
+				return
+			}
+
+			fmt.Printf("Keypair created. Send %s to your teammates:\n", keys.PublicPath())
+			fmt.Println(public)
+		},
+	}
+	generateCmd.Flags().String("label", os.Getenv("USER"), "Label stored with your public key (e.g. your email)")
+
+	// keys show command
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print your public key",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			public, err := keys.LoadPublic()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Println(public)
+		},
+	}
+
+	// keys import command
+	importCmd := &cobra.Command{
+		Use:   "import FILE",
+		Short: "Import a teammate's public key",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			name, _ := cmd.Flags().GetString("name")
+			force, _ := cmd.Flags().GetBool("force")
+
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				fmt.Println("Error reading public key:", err)
+				return
+			}
+			public, err := keys.ParsePublicKey(string(data))
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			if name == "" {
+				name = strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+			}
+			if err := keys.ImportRecipient(name, public, force); err != nil {
+				fmt.Println("Error importing key:", err)
+				return
+			}
+			fmt.Printf("Imported public key %q (%s). Share tokens with 'deecli secrets share NAME --to %s'.\n", name, public.Label, name)
+		},
+	}
+	importCmd.Flags().String("name", "", "Name to store the key under (default: file name without extension)")
+	importCmd.Flags().Bool("force", false, "Replace a key with the same name")
+
+	// keys list command
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List imported public keys",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			names, err := keys.Recipients()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if len(names) == 0 {
+				fmt.Println("No public keys imported.")
+				return
+			}
+			for _, name := range names {
+				public, err := keys.Recipient(name)
+				if err != nil {
+					fmt.Printf("%s\t(%v)\n", name, err)
+					continue
+				}
+				fmt.Printf("%s\t%s\n", name, public.Label)
+			}
+		},
+	}
+
+	// keys keyfile-create command
+	keyfileCreateCmd := &cobra.Command{
+		Use:   "keyfile-create PATH",
+		Short: "Write a new random keyfile (e.g. on a USB stick)",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := factors.CreateKeyfile(args[0]); err != nil {
+				fmt.Println("Error creating keyfile:", err)
+				return
+			}
+			fmt.Printf("Keyfile written to %s. Keep a backup: without it the store cannot be unlocked.\n", args[0])
+			fmt.Printf("Enable it with 'deecli keys factors passphrase+keyfile --keyfile %s'.\n", args[0])
+		},
+	}
+
+	// keys factors command
+	factorsCmd := &cobra.Command{
+		Use:   "factors [passphrase | keyfile | passphrase+keyfile]",
+		Short: "Show or change what is required to unlock ~/.secrets.json",
+		Long: "Without an argument, show the unlock factors of the store. With one, re-encrypt the\n" +
+			"store (or rewrap the vault key) so it requires a passphrase, a keyfile, or both.\n" +
+			"The keyfile path is recorded in ~/.secrets.settings.json; $" + factors.KeyfileEnv + " overrides it.",
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			keyfile, _ := cmd.Flags().GetString("keyfile")
+
+			current, err := settings.Load()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if len(args) == 0 {
+				fmt.Println("Factors:", current.FactorMode())
+				if current.FactorMode() != settings.FactorPassphrase {
+					fmt.Println("Keyfile:", factors.KeyfilePath(current))
+				}
+				return
+			}
+
+			next := current
+			next.Factors = args[0]
+			switch next.Factors {
+			case settings.FactorPassphrase:
+				next.Keyfile = ""
+			case settings.FactorKeyfile, settings.FactorPassphraseKeyfile:
+				if keyfile != "" {
+					if next.Keyfile, err = filepath.Abs(keyfile); err != nil {
+						fmt.Println("Error:", err)
+						return
+					}
+				}
+				if next.Keyfile == "" {
+					fmt.Println("Error: --keyfile is required")
+					return
+				}
+			default:
+				fmt.Printf("Error: unknown factors %q (use passphrase, keyfile or passphrase+keyfile)\n", args[0])
+				return
+			}
+
+			oldSecret, err := factors.Read("Enter current passphrase: ")
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			newSecret, err := factors.ReadFor(next, "Enter new passphrase: ")
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			if err := encryptonite.Rekey(oldSecret, newSecret); err != nil {
+				fmt.Println("Error re-encrypting store:", err)
+				return
+			}
+			if err := next.Save(); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Println("Store now unlocks with:", next.FactorMode())
+		},
+	}
+	factorsCmd.Flags().String("keyfile", "", "Keyfile to require (see 'deecli keys keyfile-create')")
+
+	keysCmd.AddCommand(generateCmd, showCmd, importCmd, listCmd, keyfileCreateCmd, factorsCmd)
+	return keysCmd
+}
@@ -10,15 +10,21 @@ import (
 	"io"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
+	"github.com/deeragoo/deecli/internal/store"
 	"github.com/deeragoo/deecli/internal/update"
 	"github.com/deeragoo/deecli/version"
 
 	"github.com/deeragoo/deecli/decryptonite"
 	"github.com/deeragoo/deecli/encryptonite"
-	"golang.org/x/term"
+	"github.com/deeragoo/deecli/internal/askpass"
+	"github.com/deeragoo/deecli/internal/clipboard"
+	"github.com/deeragoo/deecli/internal/factors"
+	"github.com/deeragoo/deecli/internal/settings"
 )
 
 // Version command
@@ -130,7 +136,18 @@ func main() {
 	rootCmd := &cobra.Command{
 		Use:   "deecli",
 		Short: "deecli is an all-in-one developer shortcut CLI",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			fd, _ := cmd.Flags().GetInt("passphrase-fd")
+			file, _ := cmd.Flags().GetString("passphrase-file")
+			askpass.Configure(fd, file)
+
+			if s, err := settings.Load(); err == nil {
+				store.SetRetention(s.HistoryPolicy().Retention())
+			}
+		},
 	}
+	rootCmd.PersistentFlags().Int("passphrase-fd", -1, "Read the passphrase from this file descriptor instead of the terminal")
+	rootCmd.PersistentFlags().String("passphrase-file", "", "Read the passphrase from the first line of this file")
 
 	// AWS S3 list command
 	awsListCmd := &cobra.Command{
@@ -271,21 +288,96 @@ func main() {
 	// Encrypt token command
 	encryptTokenCmd := &cobra.Command{
 		Use:   "encrypt-token",
-		Short: "Interactively encrypt a token and save to ~/.secrets.json",
+		Short: "Encrypt a token and save to ~/.secrets.json",
 		Run: func(cmd *cobra.Command, args []string) {
-			err := encryptonite.EncryptTokenInteractive()
+			name, _ := cmd.Flags().GetString("name")
+			force, _ := cmd.Flags().GetBool("force")
+
+			// Check before stdin is consumed, so a piped secret is not read
+			// only to be thrown away
+			if cmd.Flags().Changed("from-stdin") && name == "" {
+				fmt.Println("Error: --name is required with --from-stdin")
+				return
+			}
+			value, err := tokenValueFromFlags(cmd)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			opts := encryptonite.EncryptOptions{
+				Name:  name,
+				Value: value,
+				Force: force,
+			}
+			if cmd.Flags().Changed("tag") {
+				opts.Tags, _ = cmd.Flags().GetStringSlice("tag")
+			}
+			opts.Description, _ = cmd.Flags().GetString("description")
+			opts.Service, _ = cmd.Flags().GetString("service")
+			opts.Owner, _ = cmd.Flags().GetString("owner")
+			if expires, _ := cmd.Flags().GetString("expires"); expires != "" {
+				opts.ExpiresAt, err = parseExpiry(expires, time.Now())
+				if err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+			}
+
+			err = encryptonite.EncryptToken(opts)
 			if err != nil {
 				fmt.Println("Error:", err)
 				return
 			}
 		},
 	}
+	encryptTokenCmd.Flags().String("name", "", "Token name (e.g. github, aws, stripe)")
+	encryptTokenCmd.Flags().Bool("from-stdin", false, "Read the token value from stdin")
+	encryptTokenCmd.Flags().String("from-file", "", "Read the token value from a file")
+	encryptTokenCmd.Flags().String("from-env", "", "Read the token value from an environment variable")
+	encryptTokenCmd.Flags().Bool("force", false, "Overwrite an existing token without asking")
+	encryptTokenCmd.Flags().StringSlice("tag", nil, "Tag the token (repeatable or comma-separated)")
+	encryptTokenCmd.Flags().String("description", "", "Free-form description of the token")
+	encryptTokenCmd.Flags().String("service", "", "Service the token belongs to (e.g. GitHub, Stripe)")
+	encryptTokenCmd.Flags().String("owner", "", "Person or team responsible for the token")
+	encryptTokenCmd.Flags().String("expires", "", "Expiry date (YYYY-MM-DD, RFC 3339, or relative such as 90d)")
+	encryptTokenCmd.MarkFlagsMutuallyExclusive("from-stdin", "from-file", "from-env")
 
 	// decrypt-token command
 	decryptTokenCmd := &cobra.Command{
-		Use:   "decrypt-token",
+		Use:   "decrypt-token [NAME]",
 		Short: "Decrypt and display a token from ~/.secrets.json",
+		Long: "Decrypt and display a token from ~/.secrets.json, prompting for its name.\n\n" +
+			"With --clip NAME the token is copied to the clipboard instead of printed (via wl-copy,\n" +
+			"xclip, xsel or pbcopy, or the tool named in $" + clipboard.ToolEnv + ") and cleared after\n" +
+			"--clear-after, unless something else has been copied in the meantime.",
+		Args: cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
+			clip, _ := cmd.Flags().GetBool("clip")
+			clearAfter, _ := cmd.Flags().GetDuration("clear-after")
+
+			if clip || len(args) > 0 {
+				if !clip || len(args) == 0 {
+					fmt.Println("Error: use --clip together with a token name")
+					return
+				}
+				token, err := decryptonite.GetTokenByName(args[0])
+				if err != nil {
+					fmt.Println("Error decrypting token:", err)
+					return
+				}
+				if err := copyToClipboard(token, clearAfter); err != nil {
+					fmt.Println("Error copying token:", err)
+					return
+				}
+				if clearAfter > 0 {
+					fmt.Printf("Copied %q to the clipboard; clearing it in %s.\n", args[0], clearAfter)
+				} else {
+					fmt.Printf("Copied %q to the clipboard.\n", args[0])
+				}
+				return
+			}
+
 			token, err := decryptonite.GetTokenFromSecrets()
 			if err != nil {
 				fmt.Println("Error decrypting token:", err)
@@ -295,6 +387,8 @@ func main() {
 			fmt.Println(token)
 		},
 	}
+	decryptTokenCmd.Flags().Bool("clip", false, "Copy the token to the clipboard instead of printing it")
+	decryptTokenCmd.Flags().Duration("clear-after", defaultClearAfter, "Clear the clipboard after this long (0 keeps it)")
 	
 	
 // delete-token command
@@ -302,23 +396,10 @@ var deleteTokenCmd = &cobra.Command{
 	Use:   "delete-token",
 	Short: "Delete a token from ~/.secrets.json after verifying passphrase",
 	Run: func(cmd *cobra.Command, args []string) {
-		secretsFile := os.Getenv("HOME") + "/.secrets.json"
-		secrets := encryptonite.Secrets{}
-
 		// Load secrets
-		f, err := os.Open(secretsFile)
+		secrets, err := store.OpenDefault()
 		if err != nil {
-			fmt.Println("Error opening secrets file:", err)
-			return
-		}
-		defer func() {
-			if cerr := f.Close(); cerr != nil {
-				fmt.Println("Warning: failed to close file:", cerr)
-			}
-		}()
-
-		if err := json.NewDecoder(f).Decode(&secrets); err != nil {
-			fmt.Println("Error decoding secrets file:", err)
+			fmt.Println("Error loading secrets file:", err)
 			return
 		}
 
@@ -327,7 +408,7 @@ var deleteTokenCmd = &cobra.Command{
 		tokenName, _ := bufio.NewReader(os.Stdin).ReadString('\n')
 		tokenName = strings.TrimSpace(tokenName)
 
-		encryptedToken, exists := secrets[tokenName]
+		encryptedToken, exists := secrets.Get(tokenName)
 		if !exists {
 			fmt.Printf("Token %q not found.\n", tokenName)
 			return
@@ -343,44 +424,26 @@ var deleteTokenCmd = &cobra.Command{
 		}
 
 		// Ask for passphrase to verify
-		fmt.Print("Enter passphrase for token to confirm deletion: ")
-		passBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
-		fmt.Println()
+		passphrase, err := factors.Read("Enter passphrase for token to confirm deletion: ")
 		if err != nil {
 			fmt.Println("Error reading passphrase:", err)
 			return
 		}
-		passphrase := strings.TrimSpace(string(passBytes))
 
 		// Attempt to decrypt to verify passphrase
-		_, err = decryptonite.Decrypt(encryptedToken, passphrase)
+		_, err = decryptonite.Decrypt(tokenName, encryptedToken, passphrase)
 		if err != nil {
 			fmt.Println("Passphrase incorrect or decryption failed. Aborting deletion.")
 			return
 		}
 
 		// Delete the token
-		delete(secrets, tokenName)
-
-		// Save updated secrets
-		fw, err := os.Create(secretsFile)
-		if err != nil {
-			fmt.Println("Error writing secrets file:", err)
+		if err := encryptonite.SignIndex(secrets); err != nil {
+			fmt.Println("Error unlocking vault:", err)
 			return
 		}
-		defer func() {
-			if cerr := fw.Close(); cerr != nil {
-				fmt.Println("Warning: failed to close file:", cerr)
-			}
-		}()
-
-		encJSON, err := json.MarshalIndent(secrets, "", "  ")
-		if err != nil {
-			fmt.Println("Error encoding secrets:", err)
-			return
-		}
-
-		if _, err := fw.Write(encJSON); err != nil {
+		secrets.Delete(tokenName)
+		if err := secrets.Save(); err != nil {
 			fmt.Println("Error saving secrets file:", err)
 			return
 		}
@@ -442,14 +505,81 @@ githubRunWorkflowCmd.Flags().String("bump", "", "Version bump type (patch, minor
 		versionCmd,
 		deleteTokenCmd,
 		githubRunWorkflowCmd,
+		newSecretsCmd(),
+		newVaultCmd(),
+		newAgentCmd(),
+		newExecCmd(),
+		newRenderCmd(),
+		newKeysCmd(),
+		newTeamCmd(),
+		newRecoveryCmd(),
+		newPolicyCmd(),
+		newOtpCmd(),
+		newClipboardClearCmd(),
+		newGitCredentialCmd(),
+		newDockerCredentialCmd(),
 	)
 
+	// Docker runs credential helpers as docker-credential-NAME ACTION
+	if args, ok := dockerCredentialArgs(); ok {
+		rootCmd.SetArgs(args)
+	}
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
 
+// tokenValueFromFlags returns the token value selected by --from-stdin,
+// --from-file or --from-env, or "" to prompt for it. A single trailing newline
+// is stripped from stdin and file input. When stdin is a terminal, the value is
+// read without echo.
+func tokenValueFromFlags(cmd *cobra.Command) (string, error) {
+	fromStdin, _ := cmd.Flags().GetBool("from-stdin")
+	fromFile, _ := cmd.Flags().GetString("from-file")
+	fromEnv, _ := cmd.Flags().GetString("from-env")
+
+	var value string
+	switch {
+	case fromStdin && term.IsTerminal(int(os.Stdin.Fd())):
+		// Reading a terminal with ReadAll would echo the token into the
+		// scrollback
+		fmt.Fprint(os.Stderr, "Enter token value: ")
+		data, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("error reading token from stdin: %w", err)
+		}
+		value = string(data)
+	case fromStdin:
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("error reading token from stdin: %w", err)
+		}
+		value = strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+	case fromFile != "":
+		data, err := os.ReadFile(fromFile)
+		if err != nil {
+			return "", fmt.Errorf("error reading token file: %w", err)
+		}
+		value = strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+	case fromEnv != "":
+		var ok bool
+		value, ok = os.LookupEnv(fromEnv)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", fromEnv)
+		}
+	default:
+		return "", nil
+	}
+
+	if value == "" {
+		return "", fmt.Errorf("token value is empty")
+	}
+	return value, nil
+}
+
 // getGitHubUsername fetches the GitHub username for the provided token
 func getGitHubUsername(token string) (string, error) {
 	req, err := http.NewRequest("GET", "https://api.github.com/user", nil)
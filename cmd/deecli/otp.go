@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/deeragoo/deecli/decryptonite"
+	"github.com/deeragoo/deecli/encryptonite"
+	"github.com/deeragoo/deecli/internal/otp"
+)
+
+// otpTag marks entries that hold TOTP seeds.
+const otpTag = "otp"
+
+// newOtpCmd builds the "otp" command group for TOTP seeds kept in
+// ~/.secrets.json.
+func newOtpCmd() *cobra.Command {
+	otpCmd := &cobra.Command{
+		Use:   "otp",
+		Short: "Store 2FA seeds and print one-time codes",
+	}
+
+	// otp add command
+	addCmd := &cobra.Command{
+		Use:   "add NAME",
+		Short: "Encrypt a TOTP seed (otpauth:// URI or base32) into ~/.secrets.json",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			force, _ := cmd.Flags().GetBool("force")
+
+			value, err := tokenValueFromFlags(cmd)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if value == "" {
+				if !term.IsTerminal(int(os.Stdin.Fd())) {
+					fmt.Println("Error: no seed given; use --from-stdin, --from-file or --from-env")
+					return
+				}
+				fmt.Fprint(os.Stderr, "Enter otpauth:// URI or base32 seed: ")
+				valueBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+				fmt.Fprintln(os.Stderr)
+				if err != nil {
+					fmt.Println("Error reading seed:", err)
+					return
+				}
+				value = string(valueBytes)
+			}
+
+			key, err := otp.Parse(value)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			flags := cmd.Flags()
+			if flags.Changed("algorithm") {
+				algorithm, _ := flags.GetString("algorithm")
+				key.Algorithm = strings.ToUpper(algorithm)
+			}
+			if flags.Changed("digits") {
+				key.Digits, _ = flags.GetInt("digits")
+			}
+			if flags.Changed("period") {
+				key.Period, _ = flags.GetInt("period")
+			}
+			if flags.Changed("issuer") {
+				key.Issuer, _ = flags.GetString("issuer")
+			}
+			if key.Account == "" {
+				key.Account = args[0]
+			}
+			if err := key.Validate(); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			opts := encryptonite.EncryptOptions{
+				Name:    args[0],
+				Value:   key.URI(),
+				Force:   force,
+				Tags:    []string{otpTag},
+				Service: key.Issuer,
+			}
+			if err := encryptonite.EncryptToken(opts); err != nil {
+				fmt.Println("Error encrypting seed:", err)
+				return
+			}
+			fmt.Printf("Get codes with 'deecli otp code %s' (%d digits, %ds, %s).\n", args[0], key.Digits, key.Period, key.Algorithm)
+		},
+	}
+	addCmd.Flags().String("algorithm", otp.SHA1, "Hash algorithm: SHA1, SHA256 or SHA512 (overrides the URI)")
+	addCmd.Flags().Int("digits", otp.DefaultDigits, "Code length (overrides the URI)")
+	addCmd.Flags().Int("period", otp.DefaultPeriod, "Seconds each code is valid (overrides the URI)")
+	addCmd.Flags().String("issuer", "", "Service name recorded with the seed (overrides the URI)")
+	addCmd.Flags().Bool("from-stdin", false, "Read the seed from stdin")
+	addCmd.Flags().String("from-file", "", "Read the seed from a file")
+	addCmd.Flags().String("from-env", "", "Read the seed from an environment variable")
+	addCmd.Flags().Bool("force", false, "Overwrite an existing token without asking")
+	addCmd.MarkFlagsMutuallyExclusive("from-stdin", "from-file", "from-env")
+
+	// otp code command
+	codeCmd := &cobra.Command{
+		Use:   "code NAME",
+		Short: "Print the current one-time code for a stored seed",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			seed, err := decryptonite.GetTokenByName(args[0])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error decrypting seed:", err)
+				os.Exit(1)
+			}
+			key, err := otp.Parse(seed)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %q does not hold a TOTP seed: %v\n", args[0], err)
+				os.Exit(1)
+			}
+
+			code, remaining, err := key.Code(time.Now())
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+			fmt.Println(code)
+			if term.IsTerminal(int(os.Stderr.Fd())) {
+				fmt.Fprintf(os.Stderr, "Valid for %s.\n", remaining)
+			}
+		},
+	}
+
+	otpCmd.AddCommand(addCmd, codeCmd)
+	return otpCmd
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/deeragoo/deecli/internal/askpass"
+	"github.com/deeragoo/deecli/internal/policy"
+	"github.com/deeragoo/deecli/internal/settings"
+)
+
+// newPolicyCmd builds the "policy" command group for the passphrase strength
+// policy, wrong-passphrase throttling and token history retention.
+func newPolicyCmd() *cobra.Command {
+	policyCmd := &cobra.Command{
+		Use:   "policy",
+		Short: "Show the passphrase, brute-force throttling and history settings",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			s, err := settings.Load()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if s.Policy == nil {
+				fmt.Println("No passphrase policy saved; showing the defaults.")
+			}
+			p := s.PassphrasePolicy()
+			fmt.Printf("Minimum length:  %d characters\n", p.MinLength)
+			fmt.Printf("Minimum entropy: %d bits\n", p.MinEntropy)
+			fmt.Printf("Blocklist:       %t\n", p.Blocklist)
+			fmt.Printf("Throttle:        %t (after %d wrong passphrases, delays double up to %s)\n", p.Throttle, policy.FreeAttempts, policy.MaxDelay)
+
+			h := s.HistoryPolicy()
+			fmt.Printf("History kept:    %d version(s) per token\n", h.Keep)
+			if h.MaxAgeDays > 0 {
+				fmt.Printf("History max age: %d day(s)\n", h.MaxAgeDays)
+			} else {
+				fmt.Println("History max age: none")
+			}
+		},
+	}
+
+	// policy set command
+	setCmd := &cobra.Command{
+		Use:   "set",
+		Short: "Change the policy stored in ~/.secrets.settings.json",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			s, err := settings.Load()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			p := s.PassphrasePolicy()
+
+			flags := cmd.Flags()
+			if flags.Changed("min-length") {
+				p.MinLength, _ = flags.GetInt("min-length")
+			}
+			if flags.Changed("min-entropy") {
+				p.MinEntropy, _ = flags.GetInt("min-entropy")
+			}
+			if flags.Changed("blocklist") {
+				p.Blocklist, _ = flags.GetBool("blocklist")
+			}
+			if flags.Changed("throttle") {
+				p.Throttle, _ = flags.GetBool("throttle")
+			}
+			if p.MinLength < 0 {
+				fmt.Println("Error: --min-length must not be negative")
+				return
+			}
+			if p.MinEntropy < 0 {
+				fmt.Println("Error: --min-entropy must not be negative")
+				return
+			}
+
+			h := s.HistoryPolicy()
+			if flags.Changed("history-keep") {
+				h.Keep, _ = flags.GetInt("history-keep")
+			}
+			if flags.Changed("history-max-age") {
+				maxAge, _ := flags.GetString("history-max-age")
+				d, err := parseDuration(maxAge)
+				if err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+				// Ages are kept in days, and truncating e.g. 12h would
+				// silently turn into 0d, which keeps everything
+				if d < 0 || d%(24*time.Hour) != 0 {
+					fmt.Printf("Error: --history-max-age must be a whole number of days, e.g. 90d or 2w, not %q\n", maxAge)
+					return
+				}
+				h.MaxAgeDays = int(d / (24 * time.Hour))
+			}
+			if h.Keep < 0 {
+				fmt.Println("Error: --history-keep must not be negative")
+				return
+			}
+
+			s.Policy = &p
+			s.History = &h
+			if err := s.Save(); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Println("Policy updated.")
+		},
+	}
+	setCmd.Flags().Int("min-length", settings.DefaultPolicy.MinLength, "Minimum passphrase length in characters (0 disables)")
+	setCmd.Flags().Int("min-entropy", settings.DefaultPolicy.MinEntropy, "Minimum estimated passphrase strength in bits (0 disables)")
+	setCmd.Flags().Bool("blocklist", settings.DefaultPolicy.Blocklist, "Reject common passwords")
+	setCmd.Flags().Bool("throttle", settings.DefaultPolicy.Throttle, "Delay decryption after repeated wrong passphrases")
+	setCmd.Flags().Int("history-keep", settings.DefaultHistory.Keep, "Earlier versions kept per token (0 keeps none)")
+	setCmd.Flags().String("history-max-age", "0d", "Drop earlier versions replaced more than this many whole days ago, e.g. 90d or 2w (0d keeps them)")
+
+	// policy check command
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Test a passphrase against the policy without storing anything",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			passphrase, err := askpass.Read("Enter passphrase to check: ")
+			if err != nil {
+				fmt.Println("Error reading passphrase:", err)
+				return
+			}
+
+			fmt.Printf("Estimated strength: %.0f bits\n", policy.Estimate(passphrase))
+			if err := policy.Enforce(passphrase); err != nil {
+				fmt.Println("Rejected:", err)
+				return
+			}
+			fmt.Println("Accepted.")
+		},
+	}
+
+	policyCmd.AddCommand(setCmd, checkCmd)
+	return policyCmd
+}
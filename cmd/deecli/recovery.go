@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/deeragoo/deecli/decryptonite"
+	"github.com/deeragoo/deecli/encryptonite"
+	"github.com/deeragoo/deecli/internal/envelope"
+	"github.com/deeragoo/deecli/internal/factors"
+	"github.com/deeragoo/deecli/internal/recovery"
+	"github.com/deeragoo/deecli/internal/store"
+	"github.com/deeragoo/deecli/internal/vault"
+)
+
+// newRecoveryCmd builds the "recovery" command group, which splits the vault
+// key or a token into Shamir shares held by different people.
+func newRecoveryCmd() *cobra.Command {
+	recoveryCmd := &cobra.Command{
+		Use:   "recovery",
+		Short: "Split the vault key or a token into recovery shares",
+	}
+
+	// recovery split command
+	splitCmd := &cobra.Command{
+		Use:   "split",
+		Short: "Split the vault key (or one token with --secret) into printable shares",
+		Long: "Split the vault key, or a single token with --secret, into --shares shares so that\n" +
+			"any --threshold of them reconstruct it with 'deecli recovery combine' and fewer reveal\n" +
+			"nothing. Hand each share to a different person.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			n, _ := cmd.Flags().GetInt("shares")
+			threshold, _ := cmd.Flags().GetInt("threshold")
+			name, _ := cmd.Flags().GetString("secret")
+			outDir, _ := cmd.Flags().GetString("out-dir")
+
+			var secret recovery.Secret
+			if name != "" {
+				token, err := decryptonite.GetTokenByName(name)
+				if err != nil {
+					fmt.Println("Error decrypting token:", err)
+					return
+				}
+				secret = recovery.Secret{Kind: recovery.KindToken, Name: name, Value: []byte(token)}
+			} else {
+				if !vault.Exists() {
+					fmt.Println("Error:", vault.ErrNotInitialized)
+					return
+				}
+				dek, err := decryptonite.UnlockVault()
+				if err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+				secret = recovery.Secret{Kind: recovery.KindVaultKey, Value: dek}
+			}
+
+			shares, err := recovery.Split(secret, n, threshold)
+			if err != nil {
+				fmt.Println("Error splitting secret:", err)
+				return
+			}
+
+			if outDir != "" {
+				if err := os.MkdirAll(outDir, 0o700); err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+				for i, share := range shares {
+					path := filepath.Join(outDir, fmt.Sprintf("share-%d.txt", i+1))
+					if err := store.WriteFile(path, []byte(share+"\n")); err != nil {
+						fmt.Println("Error writing share:", err)
+						return
+					}
+					fmt.Println("Wrote", path)
+				}
+			} else {
+				for i, share := range shares {
+					fmt.Printf("Share %d of %d:\n%s\n\n", i+1, n, share)
+				}
+			}
+			fmt.Printf("Any %d of these %d shares recover the %s. Store them apart.\n", threshold, n, describe(secret))
+		},
+	}
+	splitCmd.Flags().Int("shares", 5, "Number of shares to create")
+	splitCmd.Flags().Int("threshold", 3, "Number of shares needed to recover")
+	splitCmd.Flags().String("secret", "", "Split this token instead of the vault key")
+	splitCmd.Flags().String("out-dir", "", "Write each share to share-N.txt in this directory instead of printing")
+
+	// recovery combine command
+	combineCmd := &cobra.Command{
+		Use:   "combine [SHARE_FILE...]",
+		Short: "Rebuild the vault key or a token from shares",
+		Long: "Combine shares read from the given files, or from stdin one per line, and restore what\n" +
+			"they hold: a recovered vault key is rewrapped under a new master passphrase, and a\n" +
+			"recovered token is encrypted back into ~/.secrets.json.",
+		Run: func(cmd *cobra.Command, args []string) {
+			name, _ := cmd.Flags().GetString("name")
+			force, _ := cmd.Flags().GetBool("force")
+
+			lines, err := readShares(args)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			secret, err := recovery.Combine(lines)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Printf("Recovered the %s from %d share(s).\n", describe(secret), len(lines))
+
+			if secret.Kind == recovery.KindToken {
+				if name == "" {
+					name = secret.Name
+				}
+				err := encryptonite.EncryptToken(encryptonite.EncryptOptions{Name: name, Value: string(secret.Value), Force: force})
+				if err != nil {
+					fmt.Println("Error saving token:", err)
+				}
+				return
+			}
+
+			if err := checkVaultKey(secret.Value); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			passphrase, err := factors.ReadNew("Enter new vault passphrase: ")
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if err := vault.Restore(secret.Value, passphrase); err != nil {
+				fmt.Println("Error restoring vault:", err)
+				return
+			}
+			fmt.Println("Vault key rewrapped under the new passphrase in ~/.secrets.vault.json")
+		},
+	}
+	combineCmd.Flags().String("name", "", "Store a recovered token under this name")
+	combineCmd.Flags().Bool("force", false, "Overwrite an existing token without asking")
+
+	recoveryCmd.AddCommand(splitCmd, combineCmd)
+	return recoveryCmd
+}
+
+// describe names what a recovery secret holds.
+func describe(secret recovery.Secret) string {
+	if secret.Kind == recovery.KindToken {
+		return fmt.Sprintf("token %q", secret.Name)
+	}
+	return "vault key"
+}
+
+// readShares collects share lines from files, or from stdin when none are
+// given. Other lines, such as the "Share 1 of 5" headers, are ignored.
+func readShares(files []string) ([]string, error) {
+	var text strings.Builder
+	if len(files) > 0 {
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("error reading share: %w", err)
+			}
+			text.Write(data)
+			text.WriteByte('\n')
+		}
+	} else {
+		interactive := term.IsTerminal(int(os.Stdin.Fd()))
+		if interactive {
+			fmt.Println("Paste the shares one per line, then an empty line:")
+		}
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if interactive && strings.TrimSpace(scanner.Text()) == "" {
+				break
+			}
+			text.WriteString(scanner.Text() + "\n")
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error reading shares: %w", err)
+		}
+	}
+
+	var lines []string
+	for _, line := range strings.Split(text.String(), "\n") {
+		if recovery.IsShare(line) {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no shares found")
+	}
+	return lines, nil
+}
+
+// checkVaultKey makes sure dek opens the vault-sealed entries in
+// ~/.secrets.json before it replaces the vault header.
+func checkVaultKey(dek []byte) error {
+	secrets, err := store.OpenDefault()
+	if err != nil {
+		return err
+	}
+	for _, name := range secrets.List() {
+		encrypted, _ := secrets.Get(name)
+		env, err := envelope.Parse(encrypted)
+		if err != nil || !env.VaultSealed() {
+			continue
+		}
+		if _, err := env.OpenWithKeyFor(name, dek); err != nil {
+			return fmt.Errorf("recovered key does not open %q; the shares belong to a different vault", name)
+		}
+		return nil
+	}
+	fmt.Println("Warning: no vault-sealed tokens to check the recovered key against.")
+	return nil
+}
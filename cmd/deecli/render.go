@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/deeragoo/deecli/decryptonite"
+	"github.com/deeragoo/deecli/internal/store"
+)
+
+// newRenderCmd builds the "render" command, which fills {{ secret "name" }}
+// placeholders in a text/template file with decrypted tokens.
+func newRenderCmd() *cobra.Command {
+	renderCmd := &cobra.Command{
+		Use:   "render TEMPLATE",
+		Short: "Render a template, replacing {{ secret \"name\" }} with decrypted tokens",
+		Long: "Render TEMPLATE with Go's text/template, where {{ secret \"name\" }} expands to the\n" +
+			"decrypted token. The result is written to -o with owner-only permissions (0600).\n" +
+			"--check only verifies that every referenced token exists, without decrypting.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			out, _ := cmd.Flags().GetString("out")
+			check, _ := cmd.Flags().GetBool("check")
+			force, _ := cmd.Flags().GetBool("force")
+
+			tmpl, err := parseTemplate(args[0], func(string) (string, error) { return "", nil })
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+
+			if check {
+				if err := checkTemplate(tmpl); err != nil {
+					fmt.Fprintln(os.Stderr, "Error:", err)
+					os.Exit(1)
+				}
+				return
+			}
+
+			if out == "" && term.IsTerminal(int(os.Stdout.Fd())) && !force {
+				fmt.Fprintln(os.Stderr, "Error: refusing to print decrypted tokens to a terminal; use -o FILE or --force")
+				os.Exit(1)
+			}
+
+			if err := renderTemplate(args[0], out); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+	renderCmd.Flags().StringP("out", "o", "", "Write the result to this file (mode 0600) instead of stdout")
+	renderCmd.Flags().Bool("check", false, "Verify referenced tokens exist without decrypting anything")
+	renderCmd.Flags().Bool("force", false, "Write to stdout even when it is a terminal")
+
+	return renderCmd
+}
+
+// parseTemplate parses the file at path with secret bound to the "secret"
+// template function.
+func parseTemplate(path string, secret func(string) (string, error)) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"secret": secret}).
+		Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderTemplate executes the template at path, decrypting each referenced
+// token once, and writes the result to out or stdout.
+func renderTemplate(path, out string) error {
+	cache := map[string]string{}
+	tmpl, err := parseTemplate(path, func(name string) (string, error) {
+		if token, ok := cache[name]; ok {
+			return token, nil
+		}
+		token, err := decryptonite.GetTokenByName(name)
+		if err != nil {
+			return "", fmt.Errorf("decrypting %q: %w", name, err)
+		}
+		cache[name] = token
+		return token, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return fmt.Errorf("error rendering template: %w", err)
+	}
+
+	if out == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := store.WriteFile(out, buf.Bytes()); err != nil {
+		return fmt.Errorf("error writing %s: %w", out, err)
+	}
+	fmt.Fprintf(os.Stderr, "Rendered %s to %s (%d token(s)).\n", path, out, len(cache))
+	return nil
+}
+
+// checkTemplate reports every token referenced with a literal name in tmpl
+// and fails if any is missing from ~/.secrets.json.
+func checkTemplate(tmpl *template.Template) error {
+	var names, dynamic []string
+	seen := map[string]bool{}
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		walkSecretCalls(t.Tree.Root, func(arg parse.Node) {
+			str, ok := arg.(*parse.StringNode)
+			if !ok {
+				dynamic = append(dynamic, arg.String())
+				return
+			}
+			if !seen[str.Text] {
+				seen[str.Text] = true
+				names = append(names, str.Text)
+			}
+		})
+	}
+
+	secrets, err := store.OpenDefault()
+	if err != nil {
+		return err
+	}
+
+	missing := 0
+	for _, name := range names {
+		if _, ok := secrets.Get(name); ok {
+			fmt.Printf("ok       %s\n", name)
+		} else {
+			fmt.Printf("missing  %s\n", name)
+			missing++
+		}
+	}
+	for _, arg := range dynamic {
+		fmt.Printf("unknown  %s (name computed at render time)\n", arg)
+	}
+
+	if missing > 0 {
+		return fmt.Errorf("%d referenced token(s) missing", missing)
+	}
+	if len(names) == 0 && len(dynamic) == 0 {
+		return errors.New("template does not reference any secrets")
+	}
+	return nil
+}
+
+// walkSecretCalls calls fn with the argument of every secret call under node.
+func walkSecretCalls(node parse.Node, fn func(arg parse.Node)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkSecretCalls(child, fn)
+		}
+	case *parse.ActionNode:
+		walkSecretCalls(n.Pipe, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for i, cmd := range n.Cmds {
+			// {{ "name" | secret }} passes the previous stage as the name
+			if i > 0 && len(cmd.Args) == 1 && isSecret(cmd.Args[0]) {
+				if prev := n.Cmds[i-1]; len(prev.Args) == 1 {
+					fn(prev.Args[0])
+				} else {
+					fn(prev)
+				}
+			}
+			walkSecretCalls(cmd, fn)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) >= 2 && isSecret(n.Args[0]) {
+			fn(n.Args[1])
+		}
+		for _, arg := range n.Args {
+			walkSecretCalls(arg, fn)
+		}
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		walkSecretCalls(n.Pipe, fn)
+	}
+}
+
+// isSecret reports whether node names the secret function.
+func isSecret(node parse.Node) bool {
+	ident, ok := node.(*parse.IdentifierNode)
+	return ok && ident.Ident == "secret"
+}
+
+func walkBranch(n *parse.BranchNode, fn func(arg parse.Node)) {
+	walkSecretCalls(n.Pipe, fn)
+	walkSecretCalls(n.List, fn)
+	walkSecretCalls(n.ElseList, fn)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/deeragoo/deecli/internal/store"
+)
+
+// writeTemplate parses text as the template file render would read.
+func writeTemplate(t *testing.T, text string) *template.Template {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.tmpl")
+	if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := parseTemplate(path, func(string) (string, error) { return "", nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tmpl
+}
+
+func TestWalkSecretCalls(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"call", `{{ secret "a" }}`, []string{"a"}},
+		{"pipeline", `{{ "a" | secret }}`, []string{"a"}},
+		{"pipeline continued", `{{ "a" | secret | printf "%q" }}`, []string{"a"}},
+		{"parenthesized", `{{ len (secret "a") }}`, []string{"a"}},
+		{"parenthesized pipeline", `{{ len ("a" | secret) }}`, []string{"a"}},
+		{"variable", `{{ $v := secret "a" }}{{ $v }}`, []string{"a"}},
+		{"branches", `{{ if true }}{{ secret "a" }}{{ else }}{{ "b" | secret }}{{ end }}`, []string{"a", "b"}},
+		{"range and with", `{{ range . }}{{ secret "a" }}{{ end }}{{ with . }}{{ "b" | secret }}{{ end }}`, []string{"a", "b"}},
+		{"defined template", `{{ define "t" }}{{ secret "a" }}{{ end }}{{ template "t" }}`, []string{"a"}},
+		{"computed name", `{{ range $x := . }}{{ secret $x }}{{ end }}`, []string{"dynamic $x"}},
+		{"computed pipeline", `{{ printf "%s_key" "a" | secret }}`, []string{`dynamic printf "%s_key" "a"`}},
+		{"secret as data", `{{ "secret" }}{{ printf "secret" }}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, tmpl := range writeTemplate(t, tt.text).Templates() {
+				if tmpl.Tree == nil {
+					continue
+				}
+				walkSecretCalls(tmpl.Tree.Root, func(arg parse.Node) {
+					if str, ok := arg.(*parse.StringNode); ok {
+						got = append(got, str.Text)
+					} else {
+						got = append(got, "dynamic "+arg.String())
+					}
+				})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("found %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTemplate(t *testing.T) {
+	store.SetDefaultBackend(store.NewMemoryBackend(store.Secrets{"github": {Value: "sealed"}}))
+	t.Cleanup(func() { store.SetDefaultBackend(nil) })
+
+	tests := []struct {
+		name    string
+		text    string
+		wantErr string
+	}{
+		{"present", `token={{ secret "github" }}`, ""},
+		{"present through a pipeline", `token={{ "github" | secret }}`, ""},
+		{"missing", `{{ secret "github" }} {{ "stripe" | secret }}`, "1 referenced token(s) missing"},
+		{"only computed names", `{{ range . }}{{ secret . }}{{ end }}`, ""},
+		{"no references", `plain text`, "does not reference any secrets"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkTemplate(writeTemplate(t, tt.text))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("checkTemplate: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("checkTemplate returned %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,795 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/deeragoo/deecli/decryptonite"
+	"github.com/deeragoo/deecli/encryptonite"
+	"github.com/deeragoo/deecli/internal/askpass"
+	"github.com/deeragoo/deecli/internal/dotenv"
+	"github.com/deeragoo/deecli/internal/envelope"
+	"github.com/deeragoo/deecli/internal/generate"
+	"github.com/deeragoo/deecli/internal/keys"
+	"github.com/deeragoo/deecli/internal/store"
+)
+
+// newSecretsCmd builds the "secrets" command group for managing ~/.secrets.json.
+func newSecretsCmd() *cobra.Command {
+	secretsCmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "Manage entries in ~/.secrets.json",
+	}
+
+	// secrets migrate command
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Re-encrypt legacy entries into the current versioned format",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			kdf, _ := cmd.Flags().GetString("kdf")
+
+			params, err := envelope.ParamsFor(kdf)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			if err := encryptonite.MigrateSecrets(params); err != nil {
+				fmt.Println("Error migrating secrets:", err)
+			}
+		},
+	}
+	migrateCmd.Flags().String("kdf", string(envelope.DefaultParams.KDF), "Key derivation function for re-encrypted entries (scrypt, argon2id)")
+
+	// secrets list command
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List stored token names and metadata (never values)",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			tag, _ := cmd.Flags().GetString("tag")
+
+			secrets, err := store.OpenDefault()
+			if err != nil {
+				fmt.Println("Error loading secrets:", err)
+				return
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tFORMAT\tCREATED\tUPDATED\tEXPIRES\tTAGS")
+			for _, name := range secrets.List() {
+				entry, _ := secrets.Entry(name)
+				if tag != "" && !slices.Contains(entry.Tags, tag) {
+					continue
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+					name,
+					formatLabel(entry.Value),
+					formatTime(entry.Created),
+					formatTime(entry.Updated),
+					formatDate(entry.ExpiresAt),
+					strings.Join(entry.Tags, ","))
+			}
+			if err := w.Flush(); err != nil {
+				fmt.Println("Error writing output:", err)
+			}
+		},
+	}
+	listCmd.Flags().String("tag", "", "Only list tokens with this tag")
+
+	// secrets info command
+	infoCmd := &cobra.Command{
+		Use:   "info NAME",
+		Short: "Show the metadata and encryption format of a stored token",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			secrets, err := store.OpenDefault()
+			if err != nil {
+				fmt.Println("Error loading secrets:", err)
+				return
+			}
+
+			entry, ok := secrets.Entry(args[0])
+			if !ok {
+				fmt.Printf("Token %q not found.\n", args[0])
+				return
+			}
+
+			fmt.Printf("Name:     %s\n", args[0])
+			fmt.Printf("Format:   %s\n", formatLabel(entry.Value))
+			if env, err := envelope.Parse(entry.Value); err == nil {
+				fmt.Printf("KDF:      %s\n", env.Params)
+			}
+			fmt.Printf("Created:  %s\n", formatTime(entry.Created))
+			fmt.Printf("Updated:  %s\n", formatTime(entry.Updated))
+			fmt.Printf("Expires:  %s\n", formatDate(entry.ExpiresAt))
+			fmt.Printf("Service:  %s\n", entry.Service)
+			fmt.Printf("Owner:    %s\n", entry.Owner)
+			if entry.Username != "" {
+				fmt.Printf("Username: %s\n", entry.Username)
+			}
+			fmt.Printf("Tags:     %s\n", strings.Join(entry.Tags, ", "))
+			fmt.Printf("History:  %d earlier version(s)\n", len(entry.History))
+			fmt.Printf("Description: %s\n", entry.Description)
+		},
+	}
+
+	// secrets annotate command
+	annotateCmd := &cobra.Command{
+		Use:   "annotate NAME",
+		Short: "Set a token's description, tags, service, owner or expiry (no passphrase needed)",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			secrets, err := store.OpenDefault()
+			if err != nil {
+				fmt.Println("Error loading secrets:", err)
+				return
+			}
+
+			entry, ok := secrets.Entry(args[0])
+			if !ok {
+				fmt.Printf("Token %q not found.\n", args[0])
+				return
+			}
+
+			flags := cmd.Flags()
+			if flags.Changed("description") {
+				entry.Description, _ = flags.GetString("description")
+			}
+			if flags.Changed("tag") {
+				entry.Tags, _ = flags.GetStringSlice("tag")
+			}
+			if flags.Changed("service") {
+				entry.Service, _ = flags.GetString("service")
+			}
+			if flags.Changed("owner") {
+				entry.Owner, _ = flags.GetString("owner")
+			}
+			if flags.Changed("expires") {
+				expires, _ := flags.GetString("expires")
+				entry.ExpiresAt = time.Time{}
+				if expires != "" {
+					entry.ExpiresAt, err = parseExpiry(expires, time.Now())
+					if err != nil {
+						fmt.Println("Error:", err)
+						return
+					}
+				}
+			}
+
+			secrets.PutEntry(args[0], entry)
+			if err := secrets.Save(); err != nil {
+				fmt.Println("Error saving secrets:", err)
+				return
+			}
+			fmt.Printf("Token %q updated.\n", args[0])
+		},
+	}
+	annotateCmd.Flags().String("description", "", "Free-form description (empty to clear)")
+	annotateCmd.Flags().StringSlice("tag", nil, "Replace the tags (repeatable or comma-separated)")
+	annotateCmd.Flags().String("service", "", "Service the token belongs to (empty to clear)")
+	annotateCmd.Flags().String("owner", "", "Person or team responsible (empty to clear)")
+	annotateCmd.Flags().String("expires", "", "Expiry date (YYYY-MM-DD, RFC 3339, relative such as 90d, or empty to clear)")
+
+	// secrets expiring command
+	expiringCmd := &cobra.Command{
+		Use:   "expiring",
+		Short: "Report tokens that expire soon or have already expired",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			within, _ := cmd.Flags().GetString("within")
+
+			window, err := parseDuration(within)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			secrets, err := store.OpenDefault()
+			if err != nil {
+				fmt.Println("Error loading secrets:", err)
+				return
+			}
+
+			now := time.Now()
+			var names []string
+			for _, name := range secrets.List() {
+				if entry, _ := secrets.Entry(name); entry.ExpiresWithin(now, window) {
+					names = append(names, name)
+				}
+			}
+			if len(names) == 0 {
+				fmt.Printf("No tokens expire within %s.\n", within)
+				return
+			}
+
+			// Soonest first
+			sort.SliceStable(names, func(i, j int) bool {
+				a, _ := secrets.Entry(names[i])
+				b, _ := secrets.Entry(names[j])
+				return a.ExpiresAt.Before(b.ExpiresAt)
+			})
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tEXPIRES\tSTATUS\tSERVICE\tOWNER")
+			for _, name := range names {
+				entry, _ := secrets.Entry(name)
+				status := "expired"
+				if left := entry.ExpiresAt.Sub(now); left > 0 {
+					status = fmt.Sprintf("in %d day(s)", int(left.Hours()/24))
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, formatDate(entry.ExpiresAt), status, entry.Service, entry.Owner)
+			}
+			if err := w.Flush(); err != nil {
+				fmt.Println("Error writing output:", err)
+			}
+		},
+	}
+	expiringCmd.Flags().String("within", "14d", "Report tokens expiring within this window (e.g. 14d, 2w, 36h)")
+
+	// secrets rename command
+	renameCmd := &cobra.Command{
+		Use:   "rename OLD NEW",
+		Short: "Rename a stored token (re-sealed for the new name)",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			force, _ := cmd.Flags().GetBool("force")
+
+			if err := encryptonite.RenameToken(args[0], args[1], force, false); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Printf("Token %q renamed to %q.\n", args[0], args[1])
+		},
+	}
+	renameCmd.Flags().Bool("force", false, "Replace NEW if it already exists")
+
+	// secrets copy command
+	copyCmd := &cobra.Command{
+		Use:   "copy SRC DST",
+		Short: "Copy a stored token to a new name (re-sealed for that name)",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			force, _ := cmd.Flags().GetBool("force")
+
+			if err := encryptonite.RenameToken(args[0], args[1], force, true); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Printf("Token %q copied to %q.\n", args[0], args[1])
+		},
+	}
+	copyCmd.Flags().Bool("force", false, "Replace DST if it already exists")
+
+	// secrets export command
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write every token and its metadata to an encrypted bundle",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			out, _ := cmd.Flags().GetString("out")
+			reencrypt, _ := cmd.Flags().GetBool("reencrypt")
+			force, _ := cmd.Flags().GetBool("force")
+			passphraseFile, _ := cmd.Flags().GetString("bundle-passphrase-file")
+
+			if _, err := os.Stat(out); err == nil && !force {
+				fmt.Printf("Error: %s already exists; use --force to replace it\n", out)
+				return
+			}
+
+			if err := encryptonite.ExportSecrets(out, passphraseFile, reencrypt); err != nil {
+				fmt.Println("Error exporting secrets:", err)
+			}
+		},
+	}
+	exportCmd.Flags().String("out", "", "Path of the bundle to write (e.g. bundle.dee)")
+	exportCmd.Flags().Bool("reencrypt", false, "Re-encrypt every token under the export passphrase")
+	exportCmd.Flags().Bool("force", false, "Replace the bundle if it already exists")
+	_ = exportCmd.MarkFlagRequired("out")
+
+	// secrets import command
+	importCmd := &cobra.Command{
+		Use:   "import BUNDLE",
+		Short: "Add the tokens of an exported bundle to ~/.secrets.json",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			policy, _ := cmd.Flags().GetString("on-conflict")
+			passphraseFile, _ := cmd.Flags().GetString("bundle-passphrase-file")
+
+			if err := encryptonite.ImportSecrets(args[0], passphraseFile, encryptonite.ConflictPolicy(policy)); err != nil {
+				fmt.Println("Error importing secrets:", err)
+			}
+		},
+	}
+	importCmd.Flags().String("on-conflict", string(encryptonite.ConflictSkip), "What to do when a token name already exists (skip, overwrite, rename)")
+
+	for _, c := range []*cobra.Command{exportCmd, importCmd} {
+		c.Flags().String("bundle-passphrase-file", "", "Read the export passphrase from the first line of this file (or set "+askpass.BundleCmdEnv+")")
+	}
+
+	// secrets import-env command
+	importEnvCmd := &cobra.Command{
+		Use:   "import-env FILE",
+		Short: "Encrypt every KEY=VALUE pair of a .env file into ~/.secrets.json",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			prefix, _ := cmd.Flags().GetString("prefix")
+			force, _ := cmd.Flags().GetBool("force")
+
+			if err := encryptonite.ImportEnv(args[0], prefix, force); err != nil {
+				fmt.Println("Error importing .env file:", err)
+			}
+		},
+	}
+	importEnvCmd.Flags().String("prefix", "", "Prefix added to every token name (e.g. proj/)")
+	importEnvCmd.Flags().Bool("force", false, "Overwrite tokens that already exist")
+
+	// secrets render command
+	renderCmd := &cobra.Command{
+		Use:   "render",
+		Short: "Print decrypted tokens as dotenv, shell or JSON for one-off use",
+		Long: "Decrypt the tokens matching --select and print them to stdout. Keys are the token\n" +
+			"names without any prefix up to the last '/'. The output contains plaintext secrets,\n" +
+			"so it is refused on a terminal unless --force is given; redirect it to a file or pipe.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			format, _ := cmd.Flags().GetString("format")
+			selectors, _ := cmd.Flags().GetStringArray("select")
+			force, _ := cmd.Flags().GetBool("force")
+
+			if err := renderSecrets(format, selectors, force); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+	renderCmd.Flags().String("format", "dotenv", "Output format (dotenv, shell, json)")
+	renderCmd.Flags().StringArray("select", nil, "Glob of token names to include, e.g. 'proj/*' (repeatable; default all)")
+	renderCmd.Flags().Bool("force", false, "Write plaintext tokens even when stdout is a terminal")
+
+	// secrets generate command
+	generateCmd := &cobra.Command{
+		Use:   "generate NAME",
+		Short: "Store a fresh random key, password or diceware passphrase without printing it",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			charset, _ := cmd.Flags().GetString("charset")
+			length, _ := cmd.Flags().GetInt("length")
+			show, _ := cmd.Flags().GetBool("show")
+			force, _ := cmd.Flags().GetBool("force")
+
+			if charset == generate.CharsetWords && !cmd.Flags().Changed("length") {
+				length = generate.DefaultWords
+			}
+			value, err := generate.Generate(charset, length)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			if err := encryptonite.EncryptToken(encryptonite.EncryptOptions{Name: args[0], Value: value, Force: force}); err != nil {
+				fmt.Println("Error encrypting token:", err)
+				return
+			}
+			fmt.Printf("Generated %q from %s (about %.0f bits).\n", args[0], charset, generate.Entropy(charset, length))
+			if show {
+				fmt.Println(value)
+			}
+		},
+	}
+	generateCmd.Flags().String("charset", generate.CharsetURLSafe, "Characters to draw from: "+strings.Join(generate.Charsets, ", "))
+	generateCmd.Flags().Int("length", generate.DefaultLength, fmt.Sprintf("Number of characters; with --charset words, the number of words (%d unless set)", generate.DefaultWords))
+	generateCmd.Flags().Bool("show", false, "Print the generated value after storing it")
+	generateCmd.Flags().Bool("force", false, "Overwrite an existing token without asking")
+
+	// secrets share command
+	shareCmd := &cobra.Command{
+		Use:   "share NAME --to TEAMMATE",
+		Short: "Seal a token so only a teammate's private key can open it",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			to, _ := cmd.Flags().GetString("to")
+			out, _ := cmd.Flags().GetString("out")
+
+			recipient, err := keys.Recipient(to)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+
+			secrets, err := store.OpenDefault()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error loading secrets:", err)
+				os.Exit(1)
+			}
+			entry, ok := secrets.Entry(args[0])
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Error: token %q not found\n", args[0])
+				os.Exit(1)
+			}
+
+			token, err := decryptonite.GetTokenByName(args[0])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error decrypting token:", err)
+				os.Exit(1)
+			}
+
+			share := keys.Share{Name: args[0], Value: token, Description: entry.Description}
+			if self, err := keys.LoadPublic(); err == nil {
+				share.From = self.Label
+			}
+			sealed, err := keys.SealShare(share, recipient)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error sealing token:", err)
+				os.Exit(1)
+			}
+
+			if out == "" {
+				fmt.Println(sealed)
+				return
+			}
+			if err := store.WriteFile(out, []byte(sealed+"\n")); err != nil {
+				fmt.Fprintln(os.Stderr, "Error writing share:", err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stderr, "Token %q sealed for %s in %s\n", args[0], to, out)
+		},
+	}
+	shareCmd.Flags().String("to", "", "Name of an imported public key (see 'deecli keys list')")
+	shareCmd.Flags().StringP("out", "o", "", "Write the sealed token to a file instead of stdout")
+	_ = shareCmd.MarkFlagRequired("to")
+
+	// secrets receive command
+	receiveCmd := &cobra.Command{
+		Use:   "receive [FILE | SHARE]",
+		Short: "Open a token shared with you and add it to ~/.secrets.json",
+		Long: "Open a token sealed with 'deecli secrets share' using your private key and encrypt it\n" +
+			"into your own store. The share can be given as a file, pasted as the argument, or\n" +
+			"read from stdin.",
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			name, _ := cmd.Flags().GetString("name")
+			force, _ := cmd.Flags().GetBool("force")
+
+			var text string
+			switch {
+			case len(args) == 1 && keys.IsSealedShare(args[0]):
+				text = args[0]
+			case len(args) == 1 && args[0] != "-":
+				data, err := os.ReadFile(args[0])
+				if err != nil {
+					fmt.Println("Error reading share:", err)
+					return
+				}
+				text = string(data)
+			default:
+				data, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					fmt.Println("Error reading share:", err)
+					return
+				}
+				text = string(data)
+			}
+
+			public, err := keys.LoadPublic()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			passphrase, err := askpass.Read("Enter passphrase for your private key: ")
+			if err != nil {
+				fmt.Println("Error reading passphrase:", err)
+				return
+			}
+			private, err := keys.LoadPrivate(passphrase)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			share, err := keys.OpenShare(text, public, private)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if name == "" {
+				name = share.Name
+			}
+			if share.From != "" {
+				fmt.Printf("Received token %q from %s (sender not verified).\n", share.Name, share.From)
+			}
+
+			err = encryptonite.EncryptToken(encryptonite.EncryptOptions{
+				Name:        name,
+				Value:       share.Value,
+				Force:       force,
+				Description: share.Description,
+			})
+			if err != nil {
+				fmt.Println("Error saving token:", err)
+			}
+		},
+	}
+	receiveCmd.Flags().String("name", "", "Store the token under this name instead of the sender's")
+	receiveCmd.Flags().Bool("force", false, "Overwrite an existing token without asking")
+
+	// secrets history command
+	historyCmd := &cobra.Command{
+		Use:   "history NAME",
+		Short: "List the earlier versions kept for a token",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			secrets, err := store.OpenDefault()
+			if err != nil {
+				fmt.Println("Error loading secrets:", err)
+				return
+			}
+
+			entry, ok := secrets.Entry(args[0])
+			if !ok {
+				fmt.Printf("Token %q not found.\n", args[0])
+				return
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "VERSION\tFORMAT\tSTORED\tREPLACED")
+			fmt.Fprintf(w, "current\t%s\t%s\t-\n", formatLabel(entry.Value), formatTime(entry.Updated))
+			for i, version := range entry.History {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", i+1, formatLabel(version.Value), formatTime(version.Stored), formatTime(version.Replaced))
+			}
+			if err := w.Flush(); err != nil {
+				fmt.Println("Error writing output:", err)
+			}
+		},
+	}
+
+	// secrets rollback command
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback NAME",
+		Short: "Restore an earlier version of a token (the current value is kept in history)",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			to, _ := cmd.Flags().GetInt("to")
+
+			secrets, err := store.OpenDefault()
+			if err != nil {
+				fmt.Println("Error loading secrets:", err)
+				return
+			}
+			if err := secrets.Rollback(args[0], to); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if err := encryptonite.SignIndex(secrets); err != nil {
+				fmt.Println("Error unlocking vault:", err)
+				return
+			}
+			if err := secrets.Save(); err != nil {
+				fmt.Println("Error saving secrets:", err)
+				return
+			}
+			fmt.Printf("Token %q rolled back to version %d. Undo with 'deecli secrets rollback %s --to 1'.\n", args[0], to, args[0])
+		},
+	}
+	rollbackCmd.Flags().Int("to", 1, "Version to restore, as numbered by 'deecli secrets history' (1 is the previous value)")
+
+	// secrets prune command
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Drop earlier versions beyond the history policy (see 'deecli policy')",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			secrets, err := store.OpenDefault()
+			if err != nil {
+				fmt.Println("Error loading secrets:", err)
+				return
+			}
+
+			dropped := secrets.Prune()
+			if dropped == 0 {
+				fmt.Println("Nothing to prune.")
+				return
+			}
+			if err := secrets.Save(); err != nil {
+				fmt.Println("Error saving secrets:", err)
+				return
+			}
+			fmt.Printf("Dropped %d earlier version(s).\n", dropped)
+		},
+	}
+
+	// secrets verify command
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Detect entries deleted, added, swapped or replaced outside deecli",
+		Long: "Check that every vault-sealed token opens under its own name and that the names and\n" +
+			"values in ~/.secrets.json match the index signed in ~/.secrets.vault.json. Exits with\n" +
+			"status 1 when a problem is found. Each save signs only the tokens it changed, so\n" +
+			"earlier tampering keeps showing up until reviewed and signed with --update.\n\n" +
+			"Only vault mode is covered: without a vault there is no key to sign an index with,\n" +
+			"so deleted or replaced tokens cannot be detected. The check then reports the format\n" +
+			"of each token and fails.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			update, _ := cmd.Flags().GetBool("update")
+
+			ok, err := encryptonite.VerifySecrets(update)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error verifying secrets:", err)
+				os.Exit(1)
+			}
+			if !ok {
+				fmt.Println("Verification failed.")
+				os.Exit(1)
+			}
+			fmt.Println("Verification passed.")
+		},
+	}
+	verifyCmd.Flags().Bool("update", false, "Sign the index over the file as it stands, after reviewing the report")
+
+	secretsCmd.AddCommand(migrateCmd, listCmd, infoCmd, renameCmd, copyCmd, annotateCmd, expiringCmd, historyCmd, rollbackCmd, pruneCmd, verifyCmd, generateCmd, exportCmd, importCmd, importEnvCmd, renderCmd, shareCmd, receiveCmd)
+	return secretsCmd
+}
+
+// formatLabel summarizes the envelope format of an encrypted value.
+func formatLabel(encrypted string) string {
+	env, err := envelope.Parse(encrypted)
+	if err != nil {
+		return "invalid"
+	}
+	if env.Version == envelope.LegacyVersion {
+		return "v1 (legacy)"
+	}
+	if env.VaultSealed() {
+		return fmt.Sprintf("v%d vault", env.Version)
+	}
+	return fmt.Sprintf("v%d %s", env.Version, env.Params.KDF)
+}
+
+// formatTime renders a metadata timestamp, or "-" for entries written before
+// timestamps were recorded.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// formatDate renders an expiry date, or "-" when none is set.
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02")
+}
+
+// parseDuration accepts Go durations plus day ("14d") and week ("2w") units.
+func parseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q (use e.g. 14d, 2w, 36h)", s)
+	}
+	return d, nil
+}
+
+// parseExpiry accepts a date (YYYY-MM-DD, end of day local time), an RFC 3339
+// timestamp, or a duration relative to now such as 90d.
+func parseExpiry(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t.Add(24*time.Hour - time.Second).UTC(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	if d, err := parseDuration(s); err == nil {
+		return now.Add(d).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid expiry %q (use YYYY-MM-DD, RFC 3339, or e.g. 90d)", s)
+}
+
+// renderSecrets decrypts the tokens matching selectors and writes them to
+// stdout in format.
+func renderSecrets(format string, selectors []string, force bool) error {
+	switch format {
+	case "dotenv", "shell", "json":
+	default:
+		return fmt.Errorf("unknown format %q (use dotenv, shell or json)", format)
+	}
+	if term.IsTerminal(int(os.Stdout.Fd())) && !force {
+		return errors.New("refusing to print decrypted tokens to a terminal; redirect the output or use --force")
+	}
+	secrets, err := store.OpenDefault()
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, name := range secrets.List() {
+		if len(selectors) == 0 {
+			names = append(names, name)
+		}
+		for _, selector := range selectors {
+			matched, err := path.Match(selector, name)
+			if err != nil {
+				return fmt.Errorf("invalid --select %q: %w", selector, err)
+			}
+			if matched {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	if len(names) == 0 {
+		return errors.New("no tokens match --select")
+	}
+
+	keys := make([]string, len(names))
+	owner := map[string]string{}
+	for i, name := range names {
+		keys[i] = name[strings.LastIndex(name, "/")+1:]
+		if format != "json" && !envNamePattern.MatchString(keys[i]) {
+			return fmt.Errorf("token %q does not map to a valid variable name", name)
+		}
+		if other, dup := owner[keys[i]]; dup {
+			return fmt.Errorf("tokens %q and %q both render as %s", other, name, keys[i])
+		}
+		owner[keys[i]] = name
+	}
+
+	fmt.Fprintf(os.Stderr, "⚠️  WARNING: writing %d decrypted token(s) in plaintext. Delete the output when done.\n", len(names))
+
+	values := map[string]string{}
+	for i, name := range names {
+		token, err := decryptonite.GetTokenByName(name)
+		if err != nil {
+			return fmt.Errorf("decrypting %q: %w", name, err)
+		}
+		values[keys[i]] = token
+	}
+
+	var out strings.Builder
+	switch format {
+	case "dotenv":
+		for _, key := range keys {
+			fmt.Fprintf(&out, "%s=%s\n", key, dotenv.Quote(values[key]))
+		}
+	case "shell":
+		for _, key := range keys {
+			fmt.Fprintf(&out, "export %s='%s'\n", key, strings.ReplaceAll(values[key], "'", `'\''`))
+		}
+	case "json":
+		data, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return fmt.Errorf("JSON marshal error: %w", err)
+		}
+		out.Write(data)
+		out.WriteByte('\n')
+	}
+
+	_, err = os.Stdout.WriteString(out.String())
+	return err
+}
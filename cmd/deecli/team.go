@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/deeragoo/deecli/internal/askpass"
+	"github.com/deeragoo/deecli/internal/keys"
+	"github.com/deeragoo/deecli/internal/team"
+)
+
+// newTeamCmd builds the "team" command group for the multi-recipient vault
+// committed to a project repository.
+func newTeamCmd() *cobra.Command {
+	teamCmd := &cobra.Command{
+		Use:   "team",
+		Short: "Share secrets with a team through .deecli/vault.json in a repository",
+		Long: "Manage a team vault at .deecli/vault.json in the project. Each secret is encrypted\n" +
+			"under a data key that is wrapped for every member's public key, so the file can be\n" +
+			"committed. Members use the keypair from 'deecli keys generate'.",
+	}
+	teamCmd.PersistentFlags().String("file", "", "Team vault path (default: nearest "+team.RelPath+")")
+
+	// team init command
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Create .deecli/vault.json in the current directory with you as the first member",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			path, _ := cmd.Flags().GetString("file")
+			name, _ := cmd.Flags().GetString("name")
+			if path == "" {
+				path = team.RelPath
+			}
+
+			public, err := keys.LoadPublic()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if name == "" {
+				name = public.Label
+			}
+			if name == "" {
+				fmt.Println("Error: --name is required when your public key has no label")
+				return
+			}
+
+			v, _, err := team.Create(path, name, public)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if err := v.Save(path); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Printf("Team vault created at %s with member %q.\n", path, name)
+		},
+	}
+	initCmd.Flags().String("name", "", "Your member name (default: your public key label)")
+
+	// team add-member command
+	addMemberCmd := &cobra.Command{
+		Use:   "add-member NAME [PUBKEY_FILE]",
+		Short: "Give a teammate access to every secret in the team vault",
+		Args:  cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var public keys.PublicKey
+			var err error
+			if len(args) == 2 {
+				var data []byte
+				if data, err = os.ReadFile(args[1]); err == nil {
+					public, err = keys.ParsePublicKey(string(data))
+				}
+			} else {
+				public, err = keys.Recipient(args[0])
+			}
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			path, v, dek, err := unlockTeam(cmd)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if err := v.AddMember(dek, args[0], public); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if err := v.Save(path); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Printf("Member %q added. Commit %s to share access.\n", args[0], path)
+		},
+	}
+
+	// team remove-member command
+	removeMemberCmd := &cobra.Command{
+		Use:   "remove-member NAME",
+		Short: "Revoke a teammate and re-encrypt every secret under a new key",
+		Long: "Remove NAME and rotate the team key: every secret is re-encrypted and the new key is\n" +
+			"wrapped for the remaining members. The removed member may still have old copies of\n" +
+			"the file, so rotate the underlying credentials too.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			path, v, dek, err := unlockTeam(cmd)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if _, err := v.RemoveMember(dek, args[0]); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if err := v.Save(path); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Printf("Member %q removed; %d secret(s) re-encrypted.\n", args[0], len(v.Secrets))
+			fmt.Println("Rotate the underlying credentials: the removed member may have seen their values.")
+		},
+	}
+
+	// team members command
+	membersCmd := &cobra.Command{
+		Use:   "members",
+		Short: "List the members of the team vault",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			_, v, err := loadTeam(cmd)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			for _, name := range v.MemberNames() {
+				label := ""
+				if public, err := keys.ParsePublicKey(v.Members[name].PublicKey); err == nil {
+					label = public.Label
+				}
+				fmt.Printf("%s\t%s\n", name, label)
+			}
+		},
+	}
+
+	// team list command
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the secret names in the team vault",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			_, v, err := loadTeam(cmd)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			for _, name := range v.Names() {
+				fmt.Println(name)
+			}
+		},
+	}
+
+	// team set command
+	setCmd := &cobra.Command{
+		Use:   "set NAME",
+		Short: "Encrypt a secret into the team vault",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			value, err := tokenValueFromFlags(cmd)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			path, v, dek, err := unlockTeam(cmd)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			if value == "" {
+				if !term.IsTerminal(int(os.Stdin.Fd())) {
+					fmt.Println("Error: no value given; use --from-stdin, --from-file or --from-env")
+					return
+				}
+				fmt.Fprintf(os.Stderr, "Enter value for %s: ", args[0])
+				valueBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+				fmt.Fprintln(os.Stderr)
+				if err != nil {
+					fmt.Println("Error reading value:", err)
+					return
+				}
+				value = strings.TrimSpace(string(valueBytes))
+			}
+			if value == "" {
+				fmt.Println("Error: value must not be empty")
+				return
+			}
+
+			if err := v.Set(dek, args[0], value); err != nil {
+				fmt.Println("Error encrypting secret:", err)
+				return
+			}
+			if err := v.Save(path); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Printf("Secret %q saved to %s\n", args[0], path)
+		},
+	}
+	setCmd.Flags().Bool("from-stdin", false, "Read the value from stdin")
+	setCmd.Flags().String("from-file", "", "Read the value from a file")
+	setCmd.Flags().String("from-env", "", "Read the value from an environment variable")
+	setCmd.MarkFlagsMutuallyExclusive("from-stdin", "from-file", "from-env")
+
+	// team get command
+	getCmd := &cobra.Command{
+		Use:   "get NAME",
+		Short: "Decrypt a secret from the team vault and print it",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			_, v, dek, err := unlockTeam(cmd)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+			value, err := v.Get(dek, args[0])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+			fmt.Println(value)
+		},
+	}
+
+	// team delete command
+	deleteCmd := &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Remove a secret from the team vault",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			path, v, dek, err := unlockTeam(cmd)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if !v.Delete(dek, args[0]) {
+				fmt.Printf("Secret %q not found.\n", args[0])
+				return
+			}
+			if err := v.Save(path); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Printf("Secret %q deleted from %s\n", args[0], path)
+		},
+	}
+
+	// team sign command
+	signCmd := &cobra.Command{
+		Use:   "sign",
+		Short: "Review and trust a team vault's members and key",
+		Long: "Team vaults are signed with a MAC under the team key, so members added or secrets\n" +
+			"swapped by anyone without the key are rejected. The MAC cannot tell a new team key\n" +
+			"from an attacker's, so each machine also pins the key it first saw and refuses a\n" +
+			"changed one. Run this command after a teammate confirms a key change (e.g. they\n" +
+			"removed a member), for vaults written by older releases, or after a legitimate\n" +
+			"manual edit. Members marked + or - differ from the ones this machine last trusted.\n" +
+			"Check that every member and public key below is expected: signing makes them trusted.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			yes, _ := cmd.Flags().GetBool("yes")
+
+			path, v, err := loadTeam(cmd)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			public, private, err := loadTeamKeypair()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			dek, err := v.UnlockUnverified(public, private)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			pin, err := v.Pinned()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			for _, name := range v.MemberNames() {
+				mark := " "
+				if pin != nil && pin.Members[name] != v.Members[name].PublicKey {
+					mark = "+"
+				}
+				fmt.Printf("%s %s\t%s\n", mark, name, v.Members[name].PublicKey)
+			}
+			if pin != nil {
+				var removed []string
+				for name := range pin.Members {
+					if _, ok := v.Members[name]; !ok {
+						removed = append(removed, name)
+					}
+				}
+				sort.Strings(removed)
+				for _, name := range removed {
+					fmt.Printf("- %s\t%s\n", name, pin.Members[name])
+				}
+			}
+			if !yes {
+				if !term.IsTerminal(int(os.Stdin.Fd())) {
+					fmt.Println("Error: review the members above and re-run with --yes")
+					return
+				}
+				fmt.Printf("Trust these %d member(s) and %d secret(s)? (y/n): ", len(v.Members), len(v.Secrets))
+				confirm, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+				confirm = strings.TrimSpace(strings.ToLower(confirm))
+				if confirm != "y" && confirm != "yes" {
+					fmt.Println("Aborted by user.")
+					return
+				}
+			}
+
+			if err := v.Upgrade(dek); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if err := v.Save(path); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Printf("Signed %s. Commit it to share the signature.\n", path)
+		},
+	}
+	signCmd.Flags().Bool("yes", false, "Sign without asking for confirmation")
+
+	teamCmd.AddCommand(initCmd, addMemberCmd, removeMemberCmd, membersCmd, listCmd, setCmd, getCmd, deleteCmd, signCmd)
+	return teamCmd
+}
+
+// loadTeam reads the team vault selected by --file or found above the
+// current directory.
+func loadTeam(cmd *cobra.Command) (string, *team.Vault, error) {
+	path, _ := cmd.Flags().GetString("file")
+	if path == "" {
+		var err error
+		if path, err = team.Find("."); err != nil {
+			return "", nil, err
+		}
+	}
+	v, err := team.Load(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil {
+			path = rel
+		}
+	}
+	return path, v, nil
+}
+
+// unlockTeam loads the team vault, unwraps its data key with your private key
+// and checks the vault's MAC.
+func unlockTeam(cmd *cobra.Command) (string, *team.Vault, []byte, error) {
+	path, v, err := loadTeam(cmd)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	public, private, err := loadTeamKeypair()
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	dek, err := v.Unlock(public, private)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return path, v, dek, nil
+}
+
+// loadTeamKeypair loads your keypair, prompting for the private key's
+// passphrase.
+func loadTeamKeypair() (keys.PublicKey, *[32]byte, error) {
+	public, err := keys.LoadPublic()
+	if err != nil {
+		return keys.PublicKey{}, nil, err
+	}
+	passphrase, err := askpass.Read("Enter passphrase for your private key: ")
+	if err != nil {
+		return keys.PublicKey{}, nil, err
+	}
+	private, err := keys.LoadPrivate(passphrase)
+	if err != nil {
+		return keys.PublicKey{}, nil, err
+	}
+	return public, private, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/deeragoo/deecli/encryptonite"
+	"github.com/deeragoo/deecli/internal/askpass"
+	"github.com/deeragoo/deecli/internal/factors"
+	"github.com/deeragoo/deecli/internal/vault"
+)
+
+// newVaultCmd builds the "vault" command group for the master-passphrase mode.
+func newVaultCmd() *cobra.Command {
+	vaultCmd := &cobra.Command{
+		Use:   "vault",
+		Short: "Seal all tokens under a single master passphrase",
+	}
+
+	// vault init command
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Create a vault key wrapped by a master passphrase",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if vault.Exists() {
+				fmt.Println("Error: vault already initialized at ~/.secrets.vault.json")
+				return
+			}
+
+			fmt.Println("⚠️  WARNING: If you forget the master passphrase, every token in the vault is lost.")
+			fmt.Println()
+
+			passphrase, err := factors.ReadNew("Enter master passphrase: ")
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			dek, err := vault.Init(passphrase)
+			if err != nil {
+				fmt.Println("Error initializing vault:", err)
+				return
+			}
+			fmt.Println("Vault initialized at ~/.secrets.vault.json")
+
+			fmt.Print("Move existing tokens into the vault now? (y/n): ")
+			confirm, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			confirm = strings.TrimSpace(strings.ToLower(confirm))
+			if confirm != "y" && confirm != "yes" {
+				fmt.Println("Run 'deecli vault migrate' to move them later.")
+				return
+			}
+
+			if err := encryptonite.MigrateToVault(passphrase, dek); err != nil {
+				fmt.Println("Error migrating tokens:", err)
+			}
+		},
+	}
+
+	// vault migrate command
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Re-seal per-token encrypted entries under the vault key",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			v, err := vault.Load()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			passphrase, err := factors.Read("Enter vault passphrase: ")
+			if err != nil {
+				fmt.Println("Error reading passphrase:", err)
+				return
+			}
+
+			dek, err := v.Unlock(passphrase)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			if err := encryptonite.MigrateToVault(passphrase, dek); err != nil {
+				fmt.Println("Error migrating tokens:", err)
+			}
+		},
+	}
+
+	// vault change-passphrase command
+	changePassphraseCmd := &cobra.Command{
+		Use:   "change-passphrase",
+		Short: "Rewrap the vault key under a new master passphrase",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if !askpass.Interactive() {
+				fmt.Println("Error: change-passphrase needs both the current and new passphrase; run it from a terminal")
+				return
+			}
+
+			v, err := vault.Load()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			current, err := factors.Read("Enter current vault passphrase: ")
+			if err != nil {
+				fmt.Println("Error reading passphrase:", err)
+				return
+			}
+			if _, err := v.Unlock(current); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			next, err := factors.ReadNew("Enter new vault passphrase: ")
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			if err := v.ChangePassphrase(current, next); err != nil {
+				fmt.Println("Error changing passphrase:", err)
+				return
+			}
+			fmt.Println("Vault passphrase changed. Stored tokens were not re-encrypted.")
+		},
+	}
+
+	vaultCmd.AddCommand(initCmd, migrateCmd, changePassphraseCmd)
+	return vaultCmd
+}
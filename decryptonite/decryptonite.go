@@ -2,53 +2,35 @@ package decryptonite
 
 import (
 	"bufio"
-	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"strings"
 
-	"crypto/aes"
-	"crypto/cipher"
-
-	"golang.org/x/crypto/scrypt"
-    "golang.org/x/term"
+	"github.com/deeragoo/deecli/internal/agent"
+	"github.com/deeragoo/deecli/internal/envelope"
+	"github.com/deeragoo/deecli/internal/factors"
+	"github.com/deeragoo/deecli/internal/policy"
+	"github.com/deeragoo/deecli/internal/store"
+	"github.com/deeragoo/deecli/internal/vault"
 )
 
-type Secrets map[string]string
-
 func GetTokenFromSecrets() (string, error) {
-	secretsFile := os.Getenv("HOME") + "/.secrets.json"
-f, err := os.Open(secretsFile)
-if err != nil {
-	return "", err
-}
-defer func() {
-	if cerr := f.Close(); cerr != nil {
-		fmt.Println("Warning: failed to close file:", cerr)
-	}
-}()
-secrets := Secrets{}
-if err := json.NewDecoder(f).Decode(&secrets); err != nil {
-	return "", fmt.Errorf("error decoding secrets file: %w", err)
-}
+	secrets, err := store.OpenDefault()
+	if err != nil {
+		return "", err
+	}
 
 	fmt.Print("Enter token name to decrypt (e.g. github, aws, stripe): ")
 	tokenName, _ := bufio.NewReader(os.Stdin).ReadString('\n')
 	tokenName = strings.TrimSpace(tokenName)
 
-	encryptedToken, ok := secrets[tokenName]
-	if !ok {
-		return "", fmt.Errorf("token %q not found in secrets", tokenName)
+	encryptedToken, err := secrets.Lookup(tokenName)
+	if err != nil {
+		return "", err
 	}
 
-	fmt.Print("Enter passphrase to decrypt token: ")
-	passBytes, _ := term.ReadPassword(int(os.Stdin.Fd()))
-	fmt.Println()
-	passphrase := strings.TrimSpace(string(passBytes))
-
-	token, err := Decrypt(encryptedToken, passphrase)
+	token, err := decryptWithAgent(tokenName, encryptedToken)
 	if err != nil {
 		return "", err
 	}
@@ -64,79 +46,172 @@ if err := json.NewDecoder(f).Decode(&secrets); err != nil {
 	return token, nil
 }
 
-func Decrypt(encryptedB64, passphrase string) (string, error) {
-	data, err := base64.StdEncoding.DecodeString(encryptedB64)
+// vaultKey remembers the vault key once unlocked, so a command that both
+// reads and writes the store prompts only once.
+var vaultKey []byte
+
+// Decrypt opens the encrypted entry stored under name, in either the
+// versioned envelope format or the legacy bare base64 layout. For entries
+// sealed under the vault key, passphrase is the vault master passphrase.
+func Decrypt(name, encrypted, passphrase string) (string, error) {
+	env, err := envelope.Parse(encrypted)
 	if err != nil {
 		return "", err
 	}
 
-	if len(data) < 16+12 {
-		return "", errors.New("encrypted data too short")
+	var plaintext []byte
+	if env.VaultSealed() {
+		v, err := vault.Load()
+		if err != nil {
+			return "", err
+		}
+		dek, err := attempt(func() ([]byte, error) { return v.Unlock(passphrase) })
+		if err != nil {
+			return "", err
+		}
+		vaultKey = dek
+		if plaintext, err = openVaultSealed(env, name, dek); err != nil {
+			return "", err
+		}
+	} else {
+		plaintext, err = attempt(func() ([]byte, error) { return env.OpenFor(name, passphrase) })
+		if err != nil {
+			return "", err
+		}
 	}
 
-	salt := data[:16]
-	nonce := data[16 : 16+12]
-	ciphertext := data[16+12:]
+	return string(plaintext), nil
+}
 
-	key, err := scrypt.Key([]byte(passphrase), salt, 32768, 8, 1, 32)
+// GetTokenByName decrypts and returns a token by name silently (no prompts).
+func GetTokenByName(name string) (string, error) {
+	secrets, err := store.OpenDefault()
 	if err != nil {
 		return "", err
 	}
 
-	block, err := aes.NewCipher(key)
+	encryptedToken, err := secrets.Lookup(name)
 	if err != nil {
 		return "", err
 	}
 
-	aesGCM, err := cipher.NewGCM(block)
+	return decryptWithAgent(name, encryptedToken)
+}
+
+// decryptWithAgent decrypts the token stored under name, consulting the agent
+// before prompting. Tokens unlocked with a per-token passphrase are cached
+// as-is; for vault-sealed tokens only the vault key is cached.
+func decryptWithAgent(name, encrypted string) (string, error) {
+	tokenKey := agent.Key("token", name, encrypted)
+	if cached, ok := agent.Get(tokenKey); ok {
+		return string(cached), nil
+	}
+
+	env, err := envelope.Parse(encrypted)
 	if err != nil {
 		return "", err
 	}
 
-	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, nil)
+	if env.VaultSealed() {
+		dek, err := UnlockVault()
+		if err != nil {
+			return "", err
+		}
+		plaintext, err := openVaultSealed(env, name, dek)
+		if err != nil {
+			return "", err
+		}
+		return string(plaintext), nil
+	}
+
+	passphrase, err := factors.Read("Enter passphrase to decrypt token: ")
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := attempt(func() ([]byte, error) { return env.OpenFor(name, passphrase) })
 	if err != nil {
 		return "", err
 	}
 
+	if err := agent.Put(tokenKey, plaintext); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: failed to cache token in agent:", err)
+	}
 	return string(plaintext), nil
 }
 
+// UnlockVault returns the vault key unlocked earlier by this process or held
+// by the agent, or prompts for the vault passphrase and caches the key.
+func UnlockVault() ([]byte, error) {
+	if vaultKey != nil {
+		return vaultKey, nil
+	}
+	v, err := vault.Load()
+	if err != nil {
+		return nil, err
+	}
 
-// GetTokenByName decrypts and returns a token by name silently (no prompts).
-func GetTokenByName(name string) (string, error) {
-	secretsFile := os.Getenv("HOME") + "/.secrets.json"
-	f, err := os.Open(secretsFile)
+	agentKey := agent.Key("vault", "dek", v.Key)
+	if dek, ok := agent.Get(agentKey); ok {
+		vaultKey = dek
+		return dek, nil
+	}
+
+	passphrase, err := factors.Read("Enter vault passphrase: ")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	
-	defer func() {
-    if err := f.Close(); err != nil {
-        fmt.Println("Warning: failed to close file:", err)
-    }
-}()
-	secrets := Secrets{}
-	if err := json.NewDecoder(f).Decode(&secrets); err != nil {
-		return "", fmt.Errorf("error decoding secrets file: %w", err)
+	dek, err := attempt(func() ([]byte, error) { return v.Unlock(passphrase) })
+	if err != nil {
+		return nil, err
 	}
 
-	encryptedToken, ok := secrets[name]
-	if !ok {
-		return "", fmt.Errorf("token %q not found in secrets", name)
+	if err := agent.Put(agentKey, dek); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: failed to cache vault key in agent:", err)
 	}
+	vaultKey = dek
+	return dek, nil
+}
 
-	fmt.Print("Enter passphrase to decrypt token: ")
-	passBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
-	fmt.Println()
-	if err != nil {
-		return "", err
+// attempt runs open under the brute-force throttle: it first waits out any
+// delay earned by earlier wrong passphrases, then records whether this one
+// worked. Only authentication failures count as wrong passphrases; malformed
+// entries and rejected KDF parameters are passed through untouched. For
+// per-token entries a ciphertext moved from another name cannot be told apart
+// from a wrong passphrase, so it is counted too.
+func attempt(open func() ([]byte, error)) ([]byte, error) {
+	if err := policy.Wait(); err != nil {
+		return nil, err
 	}
-	passphrase := strings.TrimSpace(string(passBytes))
 
-	token, err := Decrypt(encryptedToken, passphrase)
+	plaintext, err := open()
 	if err != nil {
-		return "", err
+		if !wrongPassphrase(err) {
+			return nil, err
+		}
+		if ferr := policy.Failed(); ferr != nil {
+			fmt.Fprintln(os.Stderr, "Warning: failed to record wrong passphrase:", ferr)
+		}
+		return nil, err
+	}
+	if serr := policy.Succeeded(); serr != nil {
+		fmt.Fprintln(os.Stderr, "Warning: failed to reset attempt counter:", serr)
 	}
+	return plaintext, nil
+}
 
-	return token, nil
-}
\ No newline at end of file
+// wrongPassphrase reports whether err means the passphrase did not open the
+// entry or the vault key.
+func wrongPassphrase(err error) bool {
+	return errors.Is(err, envelope.ErrAuthFailed) || errors.Is(err, vault.ErrIncorrectPassphrase)
+}
+
+// openVaultSealed opens a vault-sealed entry stored under name. The vault key
+// is already known to be right, so a failure to authenticate means the
+// ciphertext belongs to another name or was altered, not a wrong passphrase.
+func openVaultSealed(env *envelope.Envelope, name string, dek []byte) ([]byte, error) {
+	plaintext, err := env.OpenWithKeyFor(name, dek)
+	if errors.Is(err, envelope.ErrAuthFailed) {
+		return nil, fmt.Errorf("token %q does not open under its name (moved from another entry or altered)", name)
+	}
+	return plaintext, err
+}
@@ -0,0 +1,421 @@
+package encryptonite
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/deeragoo/deecli/decryptonite"
+	"github.com/deeragoo/deecli/internal/askpass"
+	"github.com/deeragoo/deecli/internal/envelope"
+	"github.com/deeragoo/deecli/internal/factors"
+	"github.com/deeragoo/deecli/internal/policy"
+	"github.com/deeragoo/deecli/internal/store"
+	"github.com/deeragoo/deecli/internal/vault"
+)
+
+// BundleVersion is the export bundle format written by ExportSecrets.
+const BundleVersion = 1
+
+// ConflictPolicy decides what ImportSecrets does with a token whose name
+// already exists in ~/.secrets.json.
+type ConflictPolicy string
+
+const (
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	ConflictRename    ConflictPolicy = "rename"
+)
+
+// bundle is the plaintext of an export file. The whole document is sealed in
+// an envelope under the export passphrase, so the AEAD tag protects the
+// integrity of every entry and its metadata.
+type bundle struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+
+	// Reencrypted is set when every value is sealed under the export
+	// passphrase instead of the passphrase it had in the source store.
+	Reencrypted bool `json:"reencrypted,omitempty"`
+
+	// Vault is the source vault header, needed to open vault-sealed
+	// entries of a bundle that was not re-encrypted.
+	Vault *vault.Vault `json:"vault,omitempty"`
+
+	Secrets store.Secrets `json:"secrets"`
+}
+
+// ExportSecrets writes every token in ~/.secrets.json, with its metadata, to
+// an encrypted bundle at path. With reencrypt, each token is decrypted and
+// sealed again under the export passphrase so the importing machine only
+// needs that one passphrase. The export passphrase is read from
+// passphraseFile, if set, as askpass.ReadBundle describes.
+func ExportSecrets(path, passphraseFile string, reencrypt bool) error {
+	secrets, err := store.OpenDefault()
+	if err != nil {
+		return err
+	}
+
+	names := secrets.List()
+	if len(names) == 0 {
+		return errors.New("no tokens to export")
+	}
+
+	b := bundle{
+		Version:     BundleVersion,
+		Created:     time.Now().UTC(),
+		Reencrypted: reencrypt,
+		Secrets:     make(store.Secrets, len(names)),
+	}
+	for _, name := range names {
+		entry, _ := secrets.Entry(name)
+		b.Secrets[name] = entry
+	}
+	if vault.Exists() {
+		if b.Vault, err = vault.Load(); err != nil {
+			return err
+		}
+	}
+
+	passphrase, err := askpass.ReadBundle(passphraseFile, "Enter export passphrase: ", true)
+	if err != nil {
+		return err
+	}
+	if err := policy.Enforce(passphrase); err != nil {
+		return err
+	}
+
+	if reencrypt {
+		exported, err := resealForExport(b.Secrets, passphrase)
+		if err != nil {
+			return err
+		}
+		if exported < len(names) {
+			fmt.Printf("Exporting %d of %d token(s); skipped tokens are left out of the bundle.\n", exported, len(names))
+		}
+		b.Vault = nil
+	}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("JSON marshal error: %w", err)
+	}
+	sealed, err := envelope.Seal(data, passphrase, envelope.DefaultParams)
+	if err != nil {
+		return fmt.Errorf("encryption error: %w", err)
+	}
+	if err := store.WriteFile(path, []byte(sealed+"\n")); err != nil {
+		return fmt.Errorf("error writing bundle: %w", err)
+	}
+
+	fmt.Printf("Exported %d token(s) to %s\n", len(b.Secrets), path)
+	return nil
+}
+
+// resealForExport replaces every value in secrets with one sealed under
+// passphrase, dropping entries that could not be decrypted and the history of
+// the rest. It returns the number of entries kept.
+func resealForExport(secrets store.Secrets, passphrase string) (int, error) {
+	var dek []byte
+	pending := make(store.Secrets)
+	for name, entry := range secrets {
+		entry.History = nil
+		secrets[name] = entry
+		env, err := envelope.Parse(entry.Value)
+		if err != nil {
+			fmt.Printf("Skipping %q: %v\n", name, err)
+			delete(secrets, name)
+			continue
+		}
+		if !env.VaultSealed() {
+			pending[name] = entry
+			continue
+		}
+
+		if dek == nil {
+			if dek, err = decryptonite.UnlockVault(); err != nil {
+				return 0, err
+			}
+		}
+		plaintext, err := env.OpenWithKeyFor(name, dek)
+		if err != nil {
+			return 0, fmt.Errorf("error decrypting %q: %w", name, err)
+		}
+		if entry.Value, err = envelope.SealFor(name, plaintext, passphrase, envelope.DefaultParams); err != nil {
+			return 0, fmt.Errorf("encryption error for %q: %w", name, err)
+		}
+		secrets[name] = entry
+	}
+
+	if len(pending) > 0 {
+		// Reuse the migration loop on a scratch store so per-token passphrases
+		// are tried and prompted for the same way
+		scratch, err := store.Open(store.NewMemoryBackend(pending))
+		if err != nil {
+			return 0, err
+		}
+		names := scratch.List()
+		if _, err := reencrypt(scratch, names, []string{passphrase}, func(name string, plaintext []byte, _ string) (string, error) {
+			return envelope.SealFor(name, plaintext, passphrase, envelope.DefaultParams)
+		}); err != nil {
+			return 0, err
+		}
+		for _, name := range names {
+			value, _ := scratch.Get(name)
+			if value == pending[name].Value {
+				delete(secrets, name)
+				continue
+			}
+			entry := secrets[name]
+			entry.Value = value
+			secrets[name] = entry
+		}
+	}
+
+	return len(secrets), nil
+}
+
+// ImportSecrets adds the tokens of the bundle at path to ~/.secrets.json,
+// resolving name clashes according to policy. Tokens of a re-encrypted bundle
+// are sealed again locally, under the vault key when a vault exists. The
+// export passphrase is read from passphraseFile, if set.
+func ImportSecrets(path, passphraseFile string, policy ConflictPolicy) error {
+	switch policy {
+	case ConflictSkip, ConflictOverwrite, ConflictRename:
+	default:
+		return fmt.Errorf("unknown conflict policy %q (use skip, overwrite or rename)", policy)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading bundle: %w", err)
+	}
+
+	// Parsing first rejects KDF parameters beyond the envelope limits before
+	// anything is derived from this untrusted file
+	env, err := envelope.Parse(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("invalid bundle: %w", err)
+	}
+	passphrase, err := askpass.ReadBundle(passphraseFile, "Enter export passphrase: ", false)
+	if err != nil {
+		return err
+	}
+	plaintext, err := env.Open(passphrase)
+	if err != nil {
+		return errors.New("cannot open bundle: wrong passphrase or corrupted file")
+	}
+
+	var b bundle
+	if err := json.Unmarshal(plaintext, &b); err != nil {
+		return fmt.Errorf("error decoding bundle: %w", err)
+	}
+	if b.Version != BundleVersion {
+		return fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+
+	secrets, err := store.OpenDefault()
+	if err != nil {
+		return err
+	}
+
+	im := importer{bundle: &b, exportPassphrase: passphrase}
+	imported, skipped := 0, 0
+	for _, name := range slices.Sorted(maps.Keys(b.Secrets)) {
+		entry := b.Secrets[name]
+
+		target := name
+		if existing, ok := secrets.Entry(name); ok {
+			switch {
+			case existing.Value == entry.Value && !b.Reencrypted:
+				fmt.Printf("Skipping %q: already present.\n", name)
+				skipped++
+				continue
+			case policy == ConflictSkip:
+				fmt.Printf("Skipping %q: a token with that name exists.\n", name)
+				skipped++
+				continue
+			case policy == ConflictRename:
+				target = freeName(secrets, name)
+				fmt.Printf("Importing %q as %q.\n", name, target)
+			}
+		}
+
+		if target != name && !b.Reencrypted {
+			if env, err := envelope.Parse(entry.Value); err == nil && env.Bound() && !env.VaultSealed() {
+				fmt.Printf("Skipping %q: its passphrase-sealed value only opens under that name; export with --reencrypt to import it as %q.\n", name, target)
+				skipped++
+				continue
+			}
+		}
+
+		original := entry.Value
+		if entry.Value, err = im.localValue(name, target, entry.Value); err != nil {
+			return err
+		}
+		if entry.Value != original {
+			// Earlier versions are still sealed for the source store
+			entry.History = nil
+		}
+		secrets.PutEntry(target, entry)
+		imported++
+	}
+
+	if imported == 0 {
+		fmt.Println("No tokens imported.")
+		return nil
+	}
+	if err := im.finish(); err != nil {
+		return err
+	}
+	if err := im.signOnSave(secrets); err != nil {
+		return err
+	}
+	if err := secrets.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d token(s), skipped %d.\n", imported, skipped)
+	return nil
+}
+
+// importer turns bundle values into values that open on this machine,
+// prompting for each passphrase at most once.
+type importer struct {
+	bundle           *bundle
+	exportPassphrase string
+
+	sealer       *sealer
+	sourceKey    []byte
+	installVault bool
+}
+
+// localValue returns the value of the bundle entry name, to be stored under
+// target.
+func (im *importer) localValue(name, target, value string) (string, error) {
+	if im.bundle.Reencrypted {
+		plaintext, err := decryptonite.Decrypt(name, value, im.exportPassphrase)
+		if err != nil {
+			return "", fmt.Errorf("error decrypting %q: %w", name, err)
+		}
+		if im.sealer == nil {
+			s := &sealer{}
+			if vault.Exists() {
+				s.dek, err = decryptonite.UnlockVault()
+			} else {
+				s.passphrase, err = factors.ReadNew("Enter passphrase to encrypt imported tokens: ")
+			}
+			if err != nil {
+				return "", err
+			}
+			im.sealer = s
+		}
+		return im.sealer.seal(target, plaintext)
+	}
+
+	env, err := envelope.Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid entry %q in bundle: %w", name, err)
+	}
+	if !env.VaultSealed() {
+		return value, nil
+	}
+	if im.bundle.Vault == nil {
+		return "", fmt.Errorf("token %q is vault-sealed but the bundle has no vault key", name)
+	}
+
+	local, err := vault.Load()
+	if errors.Is(err, vault.ErrNotInitialized) {
+		// Adopt the source vault so its entries open unchanged
+		im.installVault = true
+	} else if err != nil {
+		return "", err
+	}
+	sameVault := im.installVault || local.Key == im.bundle.Vault.Key
+	if sameVault && target == name {
+		return value, nil
+	}
+
+	// Move the entry from the source key to ours, or to its new name
+	sourceKey, err := im.sourceVaultKey(sameVault)
+	if err != nil {
+		return "", err
+	}
+	localKey := sourceKey
+	if !sameVault {
+		if localKey, err = decryptonite.UnlockVault(); err != nil {
+			return "", err
+		}
+	}
+	plaintext, err := env.OpenWithKeyFor(name, sourceKey)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting %q: %w", name, err)
+	}
+	return envelope.SealWithKeyFor(target, plaintext, localKey)
+}
+
+// sourceVaultKey unlocks the vault of the exporting machine. When it is the
+// local vault as well, the local vault passphrase unlocks it.
+func (im *importer) sourceVaultKey(sameVault bool) ([]byte, error) {
+	if im.sourceKey != nil {
+		return im.sourceKey, nil
+	}
+	if sameVault && !im.installVault {
+		key, err := decryptonite.UnlockVault()
+		im.sourceKey = key
+		return key, err
+	}
+
+	passphrase, err := askpass.Read("Enter vault passphrase of the exporting machine: ")
+	if err != nil {
+		return nil, err
+	}
+	im.sourceKey, err = im.bundle.Vault.Unlock(passphrase)
+	return im.sourceKey, err
+}
+
+// finish writes the source vault header when imported entries depend on it.
+func (im *importer) finish() error {
+	if !im.installVault {
+		return nil
+	}
+	if err := im.bundle.Vault.Save(); err != nil {
+		return err
+	}
+	fmt.Println("Vault key imported to ~/.secrets.vault.json (same master passphrase as the exporting machine).")
+	return nil
+}
+
+// signOnSave makes saving secrets re-sign the index of the vault in use
+// after the import.
+func (im *importer) signOnSave(secrets *store.Store) error {
+	if im.sealer != nil && im.sealer.dek != nil {
+		im.sealer.signOnSave(secrets)
+		return nil
+	}
+	if !im.installVault {
+		return SignIndex(secrets)
+	}
+	key, err := im.sourceVaultKey(true)
+	if err != nil {
+		return err
+	}
+	vault.SignOnSave(secrets, key)
+	return nil
+}
+
+// freeName returns name with an "-imported" suffix that is not yet taken.
+func freeName(secrets *store.Store, name string) string {
+	candidate := name + "-imported"
+	for i := 2; ; i++ {
+		if _, exists := secrets.Get(candidate); !exists {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s-imported-%d", name, i)
+	}
+}
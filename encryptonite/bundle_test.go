@@ -0,0 +1,175 @@
+package encryptonite
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deeragoo/deecli/decryptonite"
+	"github.com/deeragoo/deecli/internal/askpass"
+	"github.com/deeragoo/deecli/internal/envelope"
+	"github.com/deeragoo/deecli/internal/store"
+)
+
+const (
+	sourcePassphrase = "correct horse battery staple"
+	bundlePassphrase = "purple monkey dishwasher lamp"
+	localPassphrase  = "tangerine orbit velvet canyon"
+)
+
+// machine points the default store at a fresh MemoryBackend holding tokens
+// sealed under passphrase, which askpass then returns, and gives it an agent
+// socket nothing listens on.
+func machine(t *testing.T, passphrase string, tokens map[string]string) *store.MemoryBackend {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DEECLI_AGENT_SOCK", filepath.Join(t.TempDir(), "agent.sock"))
+
+	secrets := store.Secrets{}
+	for name, value := range tokens {
+		sealed, err := envelope.SealFor(name, []byte(value), passphrase, envelope.DefaultParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+		secrets[name] = store.Entry{Value: sealed, Description: name + " token"}
+	}
+	b := store.NewMemoryBackend(secrets)
+	store.SetDefaultBackend(b)
+	t.Cleanup(func() {
+		os.RemoveAll(filepath.Dir(store.SidecarPath("vault")))
+		store.SetDefaultBackend(nil)
+	})
+
+	askpass.Configure(-1, writeFile(t, "passphrase", passphrase))
+	t.Cleanup(func() { askpass.Configure(-1, "") })
+	return b
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// export writes a bundle of the current machine's store and returns its path.
+func export(t *testing.T, reencrypt bool) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secrets.bundle")
+	if err := ExportSecrets(path, writeFile(t, "bundle-passphrase", bundlePassphrase), reencrypt); err != nil {
+		t.Fatalf("ExportSecrets: %v", err)
+	}
+	return path
+}
+
+// decrypted opens every token in b with passphrase.
+func decrypted(t *testing.T, b *store.MemoryBackend, passphrase string) map[string]string {
+	t.Helper()
+	secrets, err := b.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for name, entry := range secrets {
+		if got[name], err = decryptonite.Decrypt(name, entry.Value, passphrase); err != nil {
+			t.Fatalf("decrypting %q: %v", name, err)
+		}
+	}
+	return got
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	tokens := map[string]string{"github": "ghp_source", "stripe": "sk_source"}
+
+	tests := []struct {
+		name      string
+		reencrypt bool
+		// opensWith is the passphrase the imported tokens open under.
+		opensWith string
+	}{
+		{"as stored", false, sourcePassphrase},
+		{"re-encrypted", true, localPassphrase},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machine(t, sourcePassphrase, tokens)
+			path := export(t, tt.reencrypt)
+
+			local := machine(t, localPassphrase, nil)
+			if err := ImportSecrets(path, writeFile(t, "bundle-passphrase", bundlePassphrase), ConflictSkip); err != nil {
+				t.Fatalf("ImportSecrets: %v", err)
+			}
+
+			got := decrypted(t, local, tt.opensWith)
+			if len(got) != len(tokens) || got["github"] != tokens["github"] || got["stripe"] != tokens["stripe"] {
+				t.Errorf("imported %v, want %v", got, tokens)
+			}
+			secrets, _ := local.Load()
+			if secrets["github"].Description != "github token" {
+				t.Errorf("description %q was not carried over", secrets["github"].Description)
+			}
+		})
+	}
+}
+
+func TestBundleConflicts(t *testing.T) {
+	tests := []struct {
+		policy ConflictPolicy
+		want   map[string]string
+	}{
+		{ConflictSkip, map[string]string{"github": "ghp_local"}},
+		{ConflictOverwrite, map[string]string{"github": "ghp_source"}},
+		{ConflictRename, map[string]string{"github": "ghp_local", "github-imported": "ghp_source"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			machine(t, sourcePassphrase, map[string]string{"github": "ghp_source"})
+			path := export(t, true)
+
+			local := machine(t, localPassphrase, map[string]string{"github": "ghp_local"})
+			if err := ImportSecrets(path, writeFile(t, "bundle-passphrase", bundlePassphrase), tt.policy); err != nil {
+				t.Fatalf("ImportSecrets: %v", err)
+			}
+
+			got := decrypted(t, local, localPassphrase)
+			if len(got) != len(tt.want) {
+				t.Fatalf("imported %v, want %v", got, tt.want)
+			}
+			for name, value := range tt.want {
+				if got[name] != value {
+					t.Errorf("%s = %q, want %q", name, got[name], value)
+				}
+			}
+		})
+	}
+}
+
+func TestImportRejects(t *testing.T) {
+	machine(t, sourcePassphrase, map[string]string{"github": "ghp_source"})
+	path := export(t, false)
+	machine(t, localPassphrase, nil)
+
+	tests := []struct {
+		name       string
+		passphrase string
+		policy     ConflictPolicy
+		wantErr    string
+	}{
+		{"wrong passphrase", "not the bundle passphrase", ConflictSkip, "wrong passphrase or corrupted file"},
+		{"unknown policy", bundlePassphrase, "merge", "unknown conflict policy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ImportSecrets(path, writeFile(t, "bundle-passphrase", tt.passphrase), tt.policy)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ImportSecrets returned %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,64 +2,88 @@ package encryptonite
 
 import (
 	"bufio"
-	"bytes"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"time"
 
-	"golang.org/x/crypto/scrypt"
 	"golang.org/x/term"
+
+	"github.com/deeragoo/deecli/decryptonite"
+	"github.com/deeragoo/deecli/internal/askpass"
+	"github.com/deeragoo/deecli/internal/envelope"
+	"github.com/deeragoo/deecli/internal/factors"
+	"github.com/deeragoo/deecli/internal/store"
+	"github.com/deeragoo/deecli/internal/vault"
 )
 
-type Secrets map[string]string
+// stdin is shared by every prompt so buffered input is not lost between them
+// when answers are piped in.
+var stdin = bufio.NewReader(os.Stdin)
+
+// EncryptOptions configures EncryptToken. Empty fields are prompted for.
+type EncryptOptions struct {
+	// Name is the token name, e.g. github, aws, stripe.
+	Name string
+
+	// Value is the plaintext token. When empty it is read from stdin,
+	// without echo if stdin is a terminal.
+	Value string
+
+	// Force replaces an existing token without asking.
+	Force bool
+
+	// Metadata stored alongside the token. Empty fields and nil Tags keep
+	// the existing values when overwriting.
+	Description string
+	Tags        []string
+	Service     string
+	Owner       string
+	Username    string
+	ExpiresAt   time.Time
+
+	// Status receives the warnings and confirmation printed along the way;
+	// nil means stdout. Credential helpers pass stderr, since their stdout
+	// belongs to the protocol.
+	Status io.Writer
+}
 
 func EncryptTokenInteractive() error {
-	fmt.Println("⚠️  WARNING: If you forget this passphrase, your token cannot be recovered.")
-	fmt.Println("Save your passphrase securely (e.g., password manager).")
-	fmt.Println()
-
-	fmt.Print("Enter token name (e.g. github, aws, stripe): ")
-	tokenName, _ := bufio.NewReader(os.Stdin).ReadString('\n')
-	tokenName = strings.TrimSpace(tokenName)
-
-secrets := Secrets{}
-secretsFile := os.Getenv("HOME") + "/.secrets.json"
-
-// Load existing secrets (if any)
-fi, err := os.Stat(secretsFile)
-if err == nil {
-    if fi.Size() == 0 {
-        secrets = Secrets{} // empty file, no secrets yet
-    } else {
-        f, err := os.Open(secretsFile)
-        if err != nil {
-            return fmt.Errorf("error opening secrets file: %w", err)
-        }
-        		
-        defer func() {
-			if cerr := f.Close(); cerr != nil {
-				fmt.Println("Warning: failed to close file:", cerr)
-			}
-		}() // defer immediately after open
-
-        if err := json.NewDecoder(f).Decode(&secrets); err != nil {
-            return fmt.Errorf("error decoding secrets file: %w", err)
-        }
-    }
-} else if !os.IsNotExist(err) {
-    return fmt.Errorf("error checking secrets file: %w", err)
+	return EncryptToken(EncryptOptions{})
 }
 
+// EncryptToken encrypts a token and saves it to ~/.secrets.json, prompting
+// only for what opts leaves out.
+func EncryptToken(opts EncryptOptions) error {
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	status := opts.Status
+	if status == nil {
+		status = os.Stdout
+	}
+
+	tokenName := opts.Name
+	if tokenName == "" {
+		fmt.Print("Enter token name (e.g. github, aws, stripe): ")
+		tokenName, _ = stdin.ReadString('\n')
+		tokenName = strings.TrimSpace(tokenName)
+	}
+	if tokenName == "" {
+		return fmt.Errorf("token name must not be empty")
+	}
+
+	secrets, err := store.OpenDefault()
+	if err != nil {
+		return err
+	}
+
 	// Check for existing token
-	if _, exists := secrets[tokenName]; exists {
+	if _, exists := secrets.Get(tokenName); exists && !opts.Force {
+		if !interactive {
+			return fmt.Errorf("token %q already exists; use --force to overwrite", tokenName)
+		}
 		fmt.Printf("Token %q already exists. Overwrite? (y/n): ", tokenName)
-		confirm, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		confirm, _ := stdin.ReadString('\n')
 		confirm = strings.TrimSpace(strings.ToLower(confirm))
 		if confirm != "y" && confirm != "yes" {
 			fmt.Println("Aborted by user.")
@@ -67,88 +91,356 @@ if err == nil {
 		}
 	}
 
-	fmt.Printf("Enter value for %s token: ", tokenName)
-	tokenValue, _ := bufio.NewReader(os.Stdin).ReadString('\n')
-	tokenValue = strings.TrimSpace(tokenValue)
-
-	// Ask for passphrase (with confirmation)
-	fmt.Print("Enter passphrase to encrypt token: ")
-	passBytes, _ := term.ReadPassword(int(os.Stdin.Fd()))
-	fmt.Println()
-	passphrase := strings.TrimSpace(string(passBytes))
-
-	fmt.Print("Confirm passphrase: ")
-	confirmPassBytes, _ := term.ReadPassword(int(os.Stdin.Fd()))
-	fmt.Println()
-	confirmPassphrase := strings.TrimSpace(string(confirmPassBytes))
-
-	if passphrase != confirmPassphrase {
-		return fmt.Errorf("passphrases do not match")
+	tokenValue := opts.Value
+	if tokenValue == "" {
+		fmt.Printf("Enter value for %s token: ", tokenName)
+		if interactive {
+			valueBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Println()
+			if err != nil {
+				return fmt.Errorf("error reading token value: %w", err)
+			}
+			tokenValue = string(valueBytes)
+		} else {
+			tokenValue, _ = stdin.ReadString('\n')
+		}
+		tokenValue = strings.TrimSpace(tokenValue)
+	}
+	if tokenValue == "" {
+		return fmt.Errorf("token value must not be empty")
 	}
 
-	encrypted, err := encrypt(tokenValue, passphrase)
+	sealer := &sealer{}
+	if vault.Exists() {
+		// Unlocking verifies the vault passphrase, or reuses the key held by
+		// the agent without prompting
+		if sealer.dek, err = decryptonite.UnlockVault(); err != nil {
+			return err
+		}
+	} else {
+		if askpass.Interactive() && interactive {
+			fmt.Fprintln(status, "⚠️  WARNING: If you forget this passphrase, your token cannot be recovered.")
+			fmt.Fprintln(status, "Save your passphrase securely (e.g., password manager).")
+			fmt.Fprintln(status)
+		}
+		// Ask for passphrase (with confirmation)
+		if sealer.passphrase, err = factors.ReadNew("Enter passphrase to encrypt token: "); err != nil {
+			return err
+		}
+	}
+	encrypted, err := sealer.seal(tokenName, tokenValue)
 	if err != nil {
 		return fmt.Errorf("encryption error: %w", err)
 	}
 
 	// Save token
-	secrets[tokenName] = encrypted
+	sealer.signOnSave(secrets)
+	secrets.Put(tokenName, encrypted)
+	entry, _ := secrets.Entry(tokenName)
+	if opts.Description != "" {
+		entry.Description = opts.Description
+	}
+	if opts.Tags != nil {
+		entry.Tags = opts.Tags
+	}
+	if opts.Service != "" {
+		entry.Service = opts.Service
+	}
+	if opts.Owner != "" {
+		entry.Owner = opts.Owner
+	}
+	if opts.Username != "" {
+		entry.Username = opts.Username
+	}
+	if !opts.ExpiresAt.IsZero() {
+		entry.ExpiresAt = opts.ExpiresAt
+	}
+	secrets.PutEntry(tokenName, entry)
+	if err := secrets.Save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(status, "%s token encrypted and saved to ~/.secrets.json\n", tokenName)
+	return nil
+}
 
-	fw, err := os.Create(secretsFile)
+// MigrateSecrets re-encrypts every entry in ~/.secrets.json that still uses the
+// legacy layout, or whose KDF parameters differ from params. Each entry keeps
+// its own passphrase; the last passphrase that worked is tried first so a file
+// sharing one passphrase only prompts once.
+func MigrateSecrets(params envelope.Params) error {
+	secrets, err := store.OpenDefault()
 	if err != nil {
-		return fmt.Errorf("error creating secrets file: %w", err)
+		return err
 	}
-	defer func() {
-		if cerr := fw.Close(); cerr != nil {
-			fmt.Println("Warning: failed to close file:", cerr)
+
+	var pending []string
+	for _, name := range secrets.List() {
+		encrypted, _ := secrets.Get(name)
+		env, err := envelope.Parse(encrypted)
+		if err != nil {
+			fmt.Printf("Skipping %q: %v\n", name, err)
+			continue
+		}
+		if env.VaultSealed() || (env.Version == envelope.Version && env.Params == params) {
+			continue
 		}
-	}()
+		pending = append(pending, name)
+	}
 
-	encJSON, err := json.MarshalIndent(secrets, "", "  ")
+	if len(pending) == 0 {
+		fmt.Println("All tokens already use the current format.")
+		return nil
+	}
+	if err := SignIndex(secrets); err != nil {
+		return err
+	}
+
+	fmt.Printf("%d token(s) to migrate to format v%d (%s).\n", len(pending), envelope.Version, params.KDF)
+
+	migrated, err := reencrypt(secrets, pending, nil, func(name string, plaintext []byte, passphrase string) (string, error) {
+		return envelope.SealFor(name, plaintext, passphrase, params)
+	})
 	if err != nil {
-		return fmt.Errorf("JSON marshal error: %w", err)
+		return err
+	}
+
+	if migrated == 0 {
+		fmt.Println("No tokens migrated.")
+		return nil
 	}
 
-	if _, err := fw.Write(encJSON); err != nil {
-		return fmt.Errorf("error writing secrets file: %w", err)
+	if err := secrets.Save(); err != nil {
+		return err
 	}
 
-	fmt.Printf("%s token encrypted and saved to ~/.secrets.json\n", tokenName)
+	fmt.Printf("Migrated %d of %d token(s).\n", migrated, len(pending))
 	return nil
 }
 
-func encrypt(plaintext, passphrase string) (string, error) {
-	salt := make([]byte, 16)
-	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
-		return "", err
+// MigrateToVault re-seals every per-entry encrypted token under the vault
+// key. The vault passphrase is tried first for each entry, then the last
+// per-entry passphrase that worked, before prompting. Vault entries written
+// before names were bound to ciphertexts are re-sealed under their names.
+func MigrateToVault(vaultPassphrase string, dek []byte) error {
+	secrets, err := store.OpenDefault()
+	if err != nil {
+		return err
 	}
+	vault.SignOnSave(secrets, dek)
 
-	key, err := scrypt.Key([]byte(passphrase), salt, 32768, 8, 1, 32)
-	if err != nil {
-		return "", err
+	var pending []string
+	bound := 0
+	for _, name := range secrets.List() {
+		encrypted, _ := secrets.Get(name)
+		env, err := envelope.Parse(encrypted)
+		if err != nil {
+			fmt.Printf("Skipping %q: %v\n", name, err)
+			continue
+		}
+		if !env.VaultSealed() {
+			pending = append(pending, name)
+			continue
+		}
+		if env.Bound() {
+			continue
+		}
+
+		plaintext, err := env.OpenWithKey(dek)
+		if err != nil {
+			return fmt.Errorf("error decrypting %q: %w", name, err)
+		}
+		sealed, err := envelope.SealWithKeyFor(name, plaintext, dek)
+		if err != nil {
+			return fmt.Errorf("encryption error for %q: %w", name, err)
+		}
+		secrets.Reseal(name, sealed)
+		bound++
 	}
 
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
+	if len(pending) == 0 && bound == 0 {
+		fmt.Println("All tokens are already sealed under the vault key.")
+		return nil
 	}
 
-	aesGCM, err := cipher.NewGCM(block)
+	migrated := 0
+	if len(pending) > 0 {
+		fmt.Printf("%d token(s) to move into the vault.\n", len(pending))
+
+		migrated, err = reencrypt(secrets, pending, []string{vaultPassphrase}, func(name string, plaintext []byte, _ string) (string, error) {
+			return envelope.SealWithKeyFor(name, plaintext, dek)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if migrated == 0 && bound == 0 {
+		fmt.Println("No tokens migrated.")
+		return nil
+	}
+
+	if err := secrets.Save(); err != nil {
+		return err
+	}
+
+	if len(pending) > 0 {
+		fmt.Printf("Moved %d of %d token(s) into the vault.\n", migrated, len(pending))
+	}
+	if bound > 0 {
+		fmt.Printf("Bound %d vault token(s) to their names.\n", bound)
+	}
+	return nil
+}
+
+// reencrypt decrypts each pending entry and replaces it in secrets with the
+// output of reseal, leaving its history alone. The candidate passphrases and
+// the last passphrase that worked are tried before prompting; an empty answer
+// skips the entry. It returns the number of entries replaced; the caller
+// saves the store.
+func reencrypt(secrets *store.Store, pending, candidates []string, reseal func(name string, plaintext []byte, passphrase string) (string, error)) (int, error) {
+	var last string
+	migrated := 0
+	for _, name := range pending {
+		encrypted, _ := secrets.Get(name)
+		env, err := envelope.Parse(encrypted)
+		if err != nil {
+			return migrated, err
+		}
+
+		var plaintext []byte
+		passphrase := ""
+		for _, candidate := range append([]string{last}, candidates...) {
+			if candidate == "" {
+				continue
+			}
+			if plaintext, err = env.OpenFor(name, candidate); err == nil {
+				passphrase = candidate
+				break
+			}
+		}
+
+		if passphrase == "" {
+			candidate, err := factors.Read(fmt.Sprintf("Enter passphrase for %q (leave empty to skip): ", name))
+			if err != nil {
+				return migrated, fmt.Errorf("error reading passphrase: %w", err)
+			}
+			if candidate == "" {
+				fmt.Printf("Skipped %q.\n", name)
+				continue
+			}
+
+			plaintext, err = env.OpenFor(name, candidate)
+			if err != nil {
+				fmt.Printf("Passphrase incorrect for %q, skipping.\n", name)
+				continue
+			}
+			passphrase = candidate
+		}
+		last = passphrase
+
+		sealed, err := reseal(name, plaintext, passphrase)
+		if err != nil {
+			return migrated, fmt.Errorf("encryption error for %q: %w", name, err)
+		}
+		secrets.Reseal(name, sealed)
+		migrated++
+	}
+	return migrated, nil
+}
+
+// sealer encrypts new token values under passphrase, or under the vault key
+// dek when a vault is initialized.
+type sealer struct {
+	passphrase string
+	dek        []byte
+}
+
+// seal encrypts plaintext for storage under name.
+func (s *sealer) seal(name, plaintext string) (string, error) {
+	if s.dek == nil {
+		return envelope.SealFor(name, []byte(plaintext), s.passphrase, envelope.DefaultParams)
+	}
+	return envelope.SealWithKeyFor(name, []byte(plaintext), s.dek)
+}
+
+// signOnSave makes saving secrets re-sign the vault index.
+func (s *sealer) signOnSave(secrets *store.Store) {
+	if s.dek != nil {
+		vault.SignOnSave(secrets, s.dek)
+	}
+}
+
+// Rekey re-encrypts the store when the unlock factors change: every
+// per-token entry is re-sealed under newSecret and, with a vault, the vault
+// key is rewrapped under it too. Nothing is saved unless every per-token
+// entry could be opened, so the store never mixes old and new factors; the
+// vault passphrase is only changed once those entries are saved.
+func Rekey(oldSecret, newSecret string) error {
+	var dek []byte
+	if vault.Exists() {
+		v, err := vault.Load()
+		if err != nil {
+			return err
+		}
+		if dek, err = v.Unlock(oldSecret); err != nil {
+			return err
+		}
+	}
+
+	secrets, err := store.OpenDefault()
 	if err != nil {
-		return "", err
+		return err
+	}
+	if dek != nil {
+		vault.SignOnSave(secrets, dek)
 	}
 
-	nonce := make([]byte, aesGCM.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", err
+	var pending []string
+	for _, name := range secrets.List() {
+		encrypted, _ := secrets.Get(name)
+		if env, err := envelope.Parse(encrypted); err == nil && !env.VaultSealed() {
+			pending = append(pending, name)
+		}
 	}
 
-	ciphertext := aesGCM.Seal(nil, nonce, []byte(plaintext), nil)
+	if len(pending) > 0 {
+		rekeyed, err := reencrypt(secrets, pending, []string{oldSecret}, func(name string, plaintext []byte, _ string) (string, error) {
+			return envelope.SealFor(name, plaintext, newSecret, envelope.DefaultParams)
+		})
+		if err != nil {
+			return err
+		}
+		if rekeyed < len(pending) {
+			return fmt.Errorf("only %d of %d token(s) could be opened; nothing was changed", rekeyed, len(pending))
+		}
 
-	var buffer bytes.Buffer
-	buffer.Write(salt)
-	buffer.Write(nonce)
-	buffer.Write(ciphertext)
+		// Earlier versions are sealed under the old factors and could not be
+		// rolled back to afterwards
+		dropped := 0
+		for _, name := range pending {
+			entry, _ := secrets.Entry(name)
+			dropped += len(entry.History)
+			entry.History = nil
+			secrets.PutEntry(name, entry)
+		}
+		if err := secrets.Save(); err != nil {
+			return err
+		}
 
-	return base64.StdEncoding.EncodeToString(buffer.Bytes()), nil
-}
\ No newline at end of file
+		fmt.Printf("Re-encrypted %d token(s).\n", rekeyed)
+		if dropped > 0 {
+			fmt.Printf("Dropped %d earlier version(s) sealed under the old factors.\n", dropped)
+		}
+	}
+
+	if dek == nil {
+		return nil
+	}
+	// Reload: the save above re-signed the index kept in the vault file
+	v, err := vault.Load()
+	if err != nil {
+		return err
+	}
+	return v.ChangePassphrase(oldSecret, newSecret)
+}
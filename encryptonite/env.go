@@ -0,0 +1,78 @@
+package encryptonite
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/deeragoo/deecli/decryptonite"
+	"github.com/deeragoo/deecli/internal/dotenv"
+	"github.com/deeragoo/deecli/internal/factors"
+	"github.com/deeragoo/deecli/internal/store"
+	"github.com/deeragoo/deecli/internal/vault"
+)
+
+// ImportEnv encrypts every KEY=VALUE pair of the .env file at path into
+// ~/.secrets.json as prefix+KEY, all under one passphrase (the vault
+// passphrase when a vault exists). Existing tokens are kept unless force.
+func ImportEnv(path, prefix string, force bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	pairs, err := dotenv.Parse(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(pairs) == 0 {
+		return fmt.Errorf("no KEY=VALUE pairs found in %s", path)
+	}
+
+	secrets, err := store.OpenDefault()
+	if err != nil {
+		return err
+	}
+
+	var pending []dotenv.Pair
+	for _, pair := range pairs {
+		name := prefix + pair.Key
+		if _, exists := secrets.Get(name); exists && !force {
+			fmt.Printf("Skipping %q: token exists (use --force to overwrite).\n", name)
+			continue
+		}
+		if pair.Value == "" {
+			fmt.Printf("Skipping %q: empty value.\n", name)
+			continue
+		}
+		pending = append(pending, pair)
+	}
+	if len(pending) == 0 {
+		fmt.Println("No tokens imported.")
+		return nil
+	}
+
+	// The vault goes through the agent and the attempt throttle like
+	// EncryptToken does
+	sealer := &sealer{}
+	if vault.Exists() {
+		sealer.dek, err = decryptonite.UnlockVault()
+	} else {
+		sealer.passphrase, err = factors.ReadNew("Enter passphrase to encrypt imported tokens: ")
+	}
+	if err != nil {
+		return err
+	}
+	sealer.signOnSave(secrets)
+	for _, pair := range pending {
+		encrypted, err := sealer.seal(prefix+pair.Key, pair.Value)
+		if err != nil {
+			return fmt.Errorf("encryption error for %q: %w", prefix+pair.Key, err)
+		}
+		secrets.Put(prefix+pair.Key, encrypted)
+	}
+	if err := secrets.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d token(s) from %s. Delete the plaintext file once you have checked them.\n", len(pending), path)
+	return nil
+}
@@ -0,0 +1,243 @@
+package encryptonite
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/deeragoo/deecli/decryptonite"
+	"github.com/deeragoo/deecli/internal/envelope"
+	"github.com/deeragoo/deecli/internal/factors"
+	"github.com/deeragoo/deecli/internal/store"
+	"github.com/deeragoo/deecli/internal/vault"
+)
+
+// SignIndex makes saving secrets re-sign the vault index, unlocking the vault
+// if this process has not yet. It does nothing when no vault exists.
+func SignIndex(secrets *store.Store) error {
+	if !vault.Exists() {
+		return nil
+	}
+	dek, err := decryptonite.UnlockVault()
+	if err != nil {
+		return err
+	}
+	vault.SignOnSave(secrets, dek)
+	return nil
+}
+
+// RenameToken moves the token stored under src to dst, or copies it when keep
+// is set. Since ciphertexts are bound to their names, the value and its
+// history are re-sealed for dst, which needs the token's passphrase or the
+// vault key. Earlier versions that cannot be re-sealed are dropped.
+func RenameToken(src, dst string, force, keep bool) error {
+	secrets, err := store.OpenDefault()
+	if err != nil {
+		return err
+	}
+	if keep {
+		err = secrets.Copy(src, dst, force)
+	} else {
+		err = secrets.Rename(src, dst, force)
+	}
+	if err != nil {
+		return err
+	}
+
+	entry, _ := secrets.Entry(dst)
+	r := rebinder{src: src, dst: dst}
+	if entry.Value, err = r.current(entry.Value); err != nil {
+		return err
+	}
+	history := entry.History[:0:0]
+	for _, version := range entry.History {
+		if value, ok := r.earlier(version.Value); ok {
+			version.Value = value
+			history = append(history, version)
+		}
+	}
+	if dropped := len(entry.History) - len(history); dropped > 0 {
+		fmt.Printf("Dropped %d earlier version(s) of %q sealed under a different passphrase.\n", dropped, src)
+	}
+	entry.History = history
+	secrets.PutEntry(dst, entry)
+
+	if err := SignIndex(secrets); err != nil {
+		return err
+	}
+	return secrets.Save()
+}
+
+// rebinder re-seals values stored under src for dst with the secret that
+// opened the current value.
+type rebinder struct {
+	src, dst   string
+	passphrase string
+	dek        []byte
+}
+
+// current re-seals the current value, prompting for what it needs.
+func (r *rebinder) current(value string) (string, error) {
+	env, err := envelope.Parse(value)
+	if err != nil {
+		return "", err
+	}
+
+	var plaintext []byte
+	if env.VaultSealed() {
+		if r.dek, err = decryptonite.UnlockVault(); err != nil {
+			return "", err
+		}
+		if plaintext, err = env.OpenWithKeyFor(r.src, r.dek); err != nil {
+			return "", fmt.Errorf("error decrypting %q: %w", r.src, err)
+		}
+	} else {
+		passphrase, err := factors.Read(fmt.Sprintf("Enter passphrase for %q: ", r.src))
+		if err != nil {
+			return "", err
+		}
+		token, err := decryptonite.Decrypt(r.src, value, passphrase)
+		if err != nil {
+			return "", fmt.Errorf("error decrypting %q: %w", r.src, err)
+		}
+		r.passphrase, plaintext = passphrase, []byte(token)
+	}
+	return r.seal(env, plaintext)
+}
+
+// earlier re-seals an earlier value if the secret of the current one opens
+// it.
+func (r *rebinder) earlier(value string) (string, bool) {
+	env, err := envelope.Parse(value)
+	if err != nil {
+		return "", false
+	}
+
+	var plaintext []byte
+	switch {
+	case env.VaultSealed() && r.dek != nil:
+		plaintext, err = env.OpenWithKeyFor(r.src, r.dek)
+	case !env.VaultSealed() && r.passphrase != "":
+		plaintext, err = env.OpenFor(r.src, r.passphrase)
+	default:
+		return "", false
+	}
+	if err != nil {
+		return "", false
+	}
+
+	sealed, err := r.seal(env, plaintext)
+	return sealed, err == nil
+}
+
+func (r *rebinder) seal(env *envelope.Envelope, plaintext []byte) (string, error) {
+	if env.VaultSealed() {
+		return envelope.SealWithKeyFor(r.dst, plaintext, r.dek)
+	}
+	return envelope.SealFor(r.dst, plaintext, r.passphrase, env.Params)
+}
+
+// ErrNoIndex is returned by VerifySecrets without a vault: there is no signed
+// index, so deleted or substituted entries cannot be detected.
+var ErrNoIndex = errors.New("no vault, so no signed index: deleted or substituted entries cannot be detected (see 'deecli vault init')")
+
+// VerifySecrets checks that ~/.secrets.json has not been modified behind
+// deecli's back and prints what it finds. Every vault-sealed entry must open
+// under its own name, and the names and values must match the index signed
+// in the vault header. With update, a file whose entries all open under their
+// names is signed as it stands. It reports whether the check passed. Without
+// a vault the format of each entry is still reported, but the check fails
+// with ErrNoIndex rather than passing on what it cannot see.
+func VerifySecrets(update bool) (bool, error) {
+	secrets, err := store.DefaultBackend().Load()
+	if err != nil {
+		return false, err
+	}
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	// broken entries fail on their own; the index may additionally be stale
+	broken := false
+	var sealed []string
+	for _, name := range names {
+		env, err := envelope.Parse(secrets[name].Value)
+		switch {
+		case err != nil:
+			fmt.Printf("INVALID    %s: %v\n", name, err)
+			broken = true
+		case !env.Bound():
+			fmt.Printf("UNBOUND    %s: not bound to its name (run '%s')\n", name, migrateHint(env))
+		case env.VaultSealed():
+			sealed = append(sealed, name)
+		}
+	}
+
+	if !vault.Exists() {
+		return false, ErrNoIndex
+	}
+
+	v, err := vault.Load()
+	if err != nil {
+		return false, err
+	}
+	dek, err := decryptonite.UnlockVault()
+	if err != nil {
+		return false, err
+	}
+
+	for _, name := range sealed {
+		env, _ := envelope.Parse(secrets[name].Value)
+		if _, err := env.OpenWithKeyFor(name, dek); err != nil {
+			fmt.Printf("MISPLACED  %s: does not open under its name (ciphertext moved from another entry?)\n", name)
+			broken = true
+		}
+	}
+
+	report, err := v.CheckIndex(secrets, dek)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case report.Unsigned:
+		fmt.Println("UNSIGNED   the vault has no index yet (run 'deecli secrets verify --update')")
+	case report.Forged:
+		fmt.Println("FORGED     the index in ~/.secrets.vault.json fails its MAC")
+	}
+	for _, name := range report.Missing {
+		fmt.Printf("MISSING    %s: in the index but not in the file (deleted outside deecli)\n", name)
+	}
+	for _, name := range report.Added {
+		fmt.Printf("ADDED      %s: in the file but not in the index (added outside deecli)\n", name)
+	}
+	for _, name := range report.Changed {
+		fmt.Printf("CHANGED    %s: value differs from the index (replaced outside deecli)\n", name)
+	}
+
+	if v.Index != nil && !report.Forged {
+		fmt.Printf("Checked %d token(s) against the index signed %s.\n", len(names), v.Index.Signed.Local().Format("2006-01-02 15:04"))
+	}
+	ok := !broken && report.OK()
+	if !update || ok {
+		return ok, nil
+	}
+
+	if broken {
+		return false, errors.New("refusing to sign the index while entries are invalid or misplaced")
+	}
+	if err := vault.SignIndex(secrets, dek); err != nil {
+		return false, err
+	}
+	fmt.Printf("Signed the index over %d token(s).\n", len(names))
+	return true, nil
+}
+
+// migrateHint names the command that binds env to its name.
+func migrateHint(env *envelope.Envelope) string {
+	if env.VaultSealed() || vault.Exists() {
+		return "deecli vault migrate"
+	}
+	return "deecli secrets migrate"
+}
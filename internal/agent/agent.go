@@ -0,0 +1,261 @@
+// Package agent implements a passphrase-caching agent in the spirit of
+// ssh-agent. The agent listens on a Unix socket readable only by its owner
+// and holds unlocked tokens and vault keys in memory until their TTL expires
+// or it is locked. Clients exchange one JSON request and response per
+// connection.
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SocketEnv names the environment variable overriding the socket path.
+const SocketEnv = "DEECLI_AGENT_SOCK"
+
+// DefaultTTL is how long cached items live when no TTL is configured.
+const DefaultTTL = 15 * time.Minute
+
+const (
+	opGet    = "get"
+	opPut    = "put"
+	opLock   = "lock"
+	opStatus = "status"
+	opStop   = "stop"
+)
+
+type request struct {
+	Op    string `json:"op"`
+	Key   string `json:"key,omitempty"`
+	Value []byte `json:"value,omitempty"`
+}
+
+type response struct {
+	OK     bool    `json:"ok"`
+	Value  []byte  `json:"value,omitempty"`
+	Error  string  `json:"error,omitempty"`
+	Status *Status `json:"status,omitempty"`
+}
+
+// Status describes a running agent.
+type Status struct {
+	PID     int           `json:"pid"`
+	Socket  string        `json:"socket"`
+	Items   int           `json:"items"`
+	TTL     time.Duration `json:"ttl"`
+	Started time.Time     `json:"started"`
+}
+
+// SocketPath returns the agent socket location: $DEECLI_AGENT_SOCK, or
+// ~/.deecli/agent.sock.
+func SocketPath() string {
+	if path := os.Getenv(SocketEnv); path != "" {
+		return path
+	}
+	return filepath.Join(os.Getenv("HOME"), ".deecli", "agent.sock")
+}
+
+type item struct {
+	value   []byte
+	expires time.Time
+}
+
+// Server holds cached items in memory.
+type Server struct {
+	ttl     time.Duration
+	socket  string
+	started time.Time
+
+	mu    sync.Mutex
+	items map[string]*item
+
+	listener net.Listener
+	done     chan struct{}
+}
+
+// NewServer returns a server caching items for ttl.
+func NewServer(socket string, ttl time.Duration) *Server {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Server{
+		ttl:    ttl,
+		socket: socket,
+		items:  map[string]*item{},
+		done:   make(chan struct{}),
+	}
+}
+
+// Listen creates the socket with owner-only permissions in a directory only
+// its owner can enter, replacing a stale socket left behind by an agent that
+// is no longer running.
+func (s *Server) Listen() error {
+	if err := privateDir(filepath.Dir(s.socket)); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(s.socket); err == nil {
+		if _, err := statusAt(s.socket); err == nil {
+			return fmt.Errorf("agent already running on %s", s.socket)
+		}
+		if err := os.Remove(s.socket); err != nil {
+			return fmt.Errorf("error removing stale socket: %w", err)
+		}
+	}
+
+	l, err := listenPrivate(s.socket)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(s.socket, 0o600); err != nil {
+		_ = l.Close()
+		return fmt.Errorf("error restricting socket permissions: %w", err)
+	}
+
+	s.listener = l
+	s.started = time.Now()
+	return nil
+}
+
+// Serve accepts connections until Close is called or a stop request is
+// received. Expired items are wiped once a minute.
+func (s *Server) Serve() error {
+	go s.sweep()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		go s.handle(conn)
+	}
+}
+
+// Close wipes every cached item, stops accepting connections and removes
+// the socket.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	select {
+	case <-s.done:
+		s.mu.Unlock()
+		return nil
+	default:
+		close(s.done)
+	}
+	s.wipeLocked()
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	if rerr := os.Remove(s.socket); rerr != nil && !os.IsNotExist(rerr) && err == nil {
+		err = rerr
+	}
+	return err
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer func() {
+		if err := conn.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, "Warning: failed to close agent connection:", err)
+		}
+	}()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+
+	resp := s.dispatch(req)
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		return
+	}
+
+	if req.Op == opStop {
+		go func() {
+			if err := s.Close(); err != nil {
+				fmt.Fprintln(os.Stderr, "Warning: failed to stop agent:", err)
+			}
+		}()
+	}
+}
+
+func (s *Server) dispatch(req request) response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req.Op {
+	case opGet:
+		it, ok := s.items[req.Key]
+		if !ok {
+			return response{}
+		}
+		if time.Now().After(it.expires) {
+			wipe(it.value)
+			delete(s.items, req.Key)
+			return response{}
+		}
+		return response{OK: true, Value: append([]byte(nil), it.value...)}
+	case opPut:
+		if old, ok := s.items[req.Key]; ok {
+			wipe(old.value)
+		}
+		s.items[req.Key] = &item{value: req.Value, expires: time.Now().Add(s.ttl)}
+		return response{OK: true}
+	case opLock:
+		s.wipeLocked()
+		return response{OK: true}
+	case opStatus, opStop:
+		return response{OK: true, Status: &Status{
+			PID:     os.Getpid(),
+			Socket:  s.socket,
+			Items:   len(s.items),
+			TTL:     s.ttl,
+			Started: s.started,
+		}}
+	}
+	return response{Error: fmt.Sprintf("unknown operation %q", req.Op)}
+}
+
+func (s *Server) sweep() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			for key, it := range s.items {
+				if now.After(it.expires) {
+					wipe(it.value)
+					delete(s.items, key)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *Server) wipeLocked() {
+	for key, it := range s.items {
+		wipe(it.value)
+		delete(s.items, key)
+	}
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startAgent serves an agent with ttl on a fresh socket, which the client
+// functions then use, and stops it when the test ends.
+func startAgent(t *testing.T, ttl time.Duration) *Server {
+	t.Helper()
+	// Unix socket paths are limited to about 100 bytes, too few for some
+	// t.TempDir names
+	dir, err := os.MkdirTemp("", "deecli-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "run", "agent.sock")
+	t.Setenv(SocketEnv, socket)
+
+	s := NewServer(socket, ttl)
+	if err := s.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- s.Serve() }()
+	t.Cleanup(func() {
+		s.Close()
+		if err := <-served; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	return s
+}
+
+func TestCache(t *testing.T) {
+	startAgent(t, time.Minute)
+
+	if _, ok := Get("vault:dek:1"); ok {
+		t.Fatal("Get hit on an empty agent")
+	}
+	if err := Put("vault:dek:1", []byte("key")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Put("token:github:1", []byte("ghp_1")); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := Get("vault:dek:1"); !ok || !bytes.Equal(got, []byte("key")) {
+		t.Errorf("Get = %q, %t; want %q", got, ok, "key")
+	}
+	if err := Put("vault:dek:1", []byte("replaced")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := Get("vault:dek:1"); string(got) != "replaced" {
+		t.Errorf("Get after replacing = %q", got)
+	}
+
+	status, err := GetStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Items != 2 || status.PID != os.Getpid() || status.TTL != time.Minute {
+		t.Errorf("status %+v, want 2 items, this pid and a 1m TTL", status)
+	}
+
+	if err := Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Get("token:github:1"); ok {
+		t.Error("Get hit after Lock")
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	startAgent(t, 50*time.Millisecond)
+
+	if err := Put("token:github:1", []byte("ghp_1")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := Get("token:github:1"); ok {
+		t.Error("Get hit after the TTL")
+	}
+}
+
+func TestStop(t *testing.T) {
+	s := startAgent(t, time.Minute)
+
+	status, err := Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Socket != s.socket {
+		t.Errorf("stopped agent on %s, want %s", status.Socket, s.socket)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(s.socket); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("socket still present after Stop")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := GetStatus(); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("GetStatus returned %v, want ErrNotRunning", err)
+	}
+	if err := Put("token:github:1", []byte("ghp_1")); err != nil {
+		t.Errorf("Put without an agent returned %v, want nil", err)
+	}
+	if _, ok := Get("token:github:1"); ok {
+		t.Error("Get hit without an agent")
+	}
+}
+
+func TestListen(t *testing.T) {
+	s := startAgent(t, time.Minute)
+
+	second := NewServer(s.socket, time.Minute)
+	if err := second.Listen(); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("second Listen returned %v, want 'already running'", err)
+	}
+
+	// A socket file nobody answers on is replaced
+	stale := filepath.Join(filepath.Dir(s.socket), "stale.sock")
+	if err := os.WriteFile(stale, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	replacement := NewServer(stale, time.Minute)
+	if err := replacement.Listen(); err != nil {
+		t.Fatalf("Listen over a stale socket: %v", err)
+	}
+	if err := replacement.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	a := Key("token", "github", "sealed-1")
+	if a != Key("token", "github", "sealed-1") {
+		t.Error("Key is not deterministic")
+	}
+	for _, other := range []string{
+		Key("token", "github", "sealed-2"),
+		Key("token", "gitlab", "sealed-1"),
+		Key("vault", "github", "sealed-1"),
+	} {
+		if other == a {
+			t.Errorf("Key collided: %s", a)
+		}
+	}
+	if strings.Contains(a, "sealed-1") {
+		t.Error("Key contains the encrypted value")
+	}
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+)
+
+// ErrNotRunning is returned by client calls when no agent is listening.
+var ErrNotRunning = errors.New("agent not running (start it with 'deecli agent')")
+
+// dialTimeout keeps commands responsive when the socket is stale.
+const dialTimeout = 500 * time.Millisecond
+
+// Get returns the cached value for key. Any failure to reach the agent is
+// reported as a miss so callers fall back to prompting.
+func Get(key string) ([]byte, bool) {
+	resp, err := call(SocketPath(), request{Op: opGet, Key: key})
+	if err != nil || !resp.OK {
+		return nil, false
+	}
+	return resp.Value, true
+}
+
+// Put caches value under key for the agent's TTL. It is a no-op when no
+// agent is running.
+func Put(key string, value []byte) error {
+	_, err := call(SocketPath(), request{Op: opPut, Key: key, Value: value})
+	if errors.Is(err, ErrNotRunning) {
+		return nil
+	}
+	return err
+}
+
+// Lock wipes every item cached by the agent.
+func Lock() error {
+	_, err := call(SocketPath(), request{Op: opLock})
+	return err
+}
+
+// Stop wipes the cache and shuts the agent down.
+func Stop() (*Status, error) {
+	resp, err := call(SocketPath(), request{Op: opStop})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Status, nil
+}
+
+// GetStatus reports on the running agent.
+func GetStatus() (*Status, error) {
+	return statusAt(SocketPath())
+}
+
+func statusAt(socket string) (*Status, error) {
+	resp, err := call(socket, request{Op: opStatus})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Status, nil
+}
+
+// Key derives a cache key for an item from its kind, name and encrypted
+// form, so a token that is re-encrypted or replaced is never served stale.
+func Key(kind, name, encrypted string) string {
+	sum := sha256.Sum256([]byte(encrypted))
+	return kind + ":" + name + ":" + hex.EncodeToString(sum[:8])
+}
+
+func call(socket string, req request) (*response, error) {
+	conn, err := net.DialTimeout("unix", socket, dialTimeout)
+	if err != nil {
+		return nil, ErrNotRunning
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
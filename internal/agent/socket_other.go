@@ -0,0 +1,23 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package agent
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// Platforms without Unix permissions rely on the ACLs inherited from the
+// user's home directory.
+
+func privateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("error creating agent directory: %w", err)
+	}
+	return nil
+}
+
+func listenPrivate(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,40 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package agent
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// privateDir creates dir with mode 0700, or checks that an existing dir is a
+// directory owned by the current user that nobody else can enter. MkdirAll
+// leaves the mode of an existing directory alone, so a socket in a shared
+// directory would otherwise be open to being swapped for another.
+func privateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("error creating agent directory: %w", err)
+	}
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("error checking agent directory: %w", err)
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !fi.IsDir() || !ok || int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("agent directory %s must be a directory owned by you", dir)
+	}
+	if fi.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("agent directory %s is accessible to other users (mode %#o); run 'chmod 700 %s'", dir, fi.Mode().Perm(), dir)
+	}
+	return nil
+}
+
+// listenPrivate creates the socket at path with mode 0600 from the start,
+// rather than restricting it after other users could already connect.
+func listenPrivate(path string) (net.Listener, error) {
+	old := syscall.Umask(0o177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
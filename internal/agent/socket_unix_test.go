@@ -0,0 +1,63 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSocketPermissions(t *testing.T) {
+	s := startAgent(t, time.Minute)
+
+	for path, want := range map[string]os.FileMode{filepath.Dir(s.socket): 0o700, s.socket: 0o600} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fi.Mode().Perm(); got != want {
+			t.Errorf("%s has mode %#o, want %#o", path, got, want)
+		}
+	}
+}
+
+func TestPrivateDir(t *testing.T) {
+	dir := t.TempDir()
+
+	open := filepath.Join(dir, "open")
+	if err := os.Mkdir(open, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(open, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := privateDir(open); err == nil || !strings.Contains(err.Error(), "chmod 700") {
+		t.Errorf("privateDir of a 0755 directory returned %v", err)
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := privateDir(file); err == nil {
+		t.Error("privateDir accepted a regular file")
+	}
+
+	link := filepath.Join(dir, "link")
+	private := filepath.Join(dir, "private")
+	if err := os.Mkdir(private, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(private, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := privateDir(link); err == nil {
+		t.Error("privateDir followed a symlink")
+	}
+	if err := privateDir(private); err != nil {
+		t.Errorf("privateDir of a 0700 directory: %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+// Package askpass reads passphrases for encrypting and decrypting tokens.
+//
+// Sources are tried in order: a file descriptor (--passphrase-fd), a file
+// (--passphrase-file), the output of DEECLI_PASSPHRASE_CMD, and finally a
+// hidden prompt on the terminal. Without a configured source and without a
+// terminal on stdin, Read refuses rather than blocking on a pipe.
+//
+// Export bundles have a passphrase of their own, read by ReadBundle from
+// separate sources.
+package askpass
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// CmdEnv names the environment variable holding a command whose stdout is
+// used as the passphrase, e.g. a password manager CLI.
+const CmdEnv = "DEECLI_PASSPHRASE_CMD"
+
+// BundleCmdEnv is the counterpart of CmdEnv for the passphrase of export
+// bundles.
+const BundleCmdEnv = "DEECLI_BUNDLE_PASSPHRASE_CMD"
+
+// ErrNoSource is returned when no passphrase source is configured and stdin
+// is not a terminal.
+var ErrNoSource = errors.New("no passphrase source: stdin is not a terminal; use --passphrase-fd, --passphrase-file or " + CmdEnv)
+
+var (
+	mu     sync.Mutex
+	fd     = -1
+	file   string
+	cached *string
+)
+
+// Configure sets the non-interactive sources from command-line flags. A
+// negative fdNum or empty path disables that source.
+func Configure(fdNum int, path string) {
+	mu.Lock()
+	defer mu.Unlock()
+	fd, file, cached = fdNum, path, nil
+}
+
+// Interactive reports whether Read will prompt on the terminal.
+func Interactive() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return !configured()
+}
+
+// Read returns the passphrase from the configured source, or prompts with
+// prompt on the terminal. A non-interactive source is read once and the
+// same value is returned to every later call.
+func Read(prompt string) (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if cached != nil {
+		return *cached, nil
+	}
+
+	var (
+		value string
+		err   error
+	)
+	switch {
+	case fd >= 0:
+		value, err = readFD(fd)
+	case file != "":
+		value, err = readFile(file)
+	case os.Getenv(CmdEnv) != "":
+		value, err = runCmd(CmdEnv)
+	default:
+		return prompted(prompt)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	cached = &value
+	return value, nil
+}
+
+// ReadNew reads a passphrase for new encryption. When prompting on the
+// terminal it asks twice and rejects mismatches; non-interactive sources are
+// trusted as-is. Empty passphrases are rejected either way.
+func ReadNew(prompt string) (string, error) {
+	value, err := Read(prompt)
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "", errors.New("passphrase must not be empty")
+	}
+	if !Interactive() {
+		return value, nil
+	}
+
+	confirm, err := Read("Confirm passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if value != confirm {
+		return "", errors.New("passphrases do not match")
+	}
+	return value, nil
+}
+
+// ReadBundle reads the passphrase of an export bundle from path, the command
+// in $DEECLI_BUNDLE_PASSPHRASE_CMD, or a prompt on the terminal, confirmed
+// when fresh is set. It neither uses nor fills the value cached by Read:
+// that one unlocks the store, and sharing it would silently make the bundle
+// passphrase the same.
+func ReadBundle(path, prompt string, fresh bool) (string, error) {
+	var (
+		value string
+		err   error
+	)
+	switch {
+	case path != "":
+		value, err = readFile(path)
+	case os.Getenv(BundleCmdEnv) != "":
+		value, err = runCmd(BundleCmdEnv)
+	default:
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return "", errors.New("no bundle passphrase source: stdin is not a terminal; use --bundle-passphrase-file or " + BundleCmdEnv)
+		}
+		if value, err = prompted(prompt); err != nil {
+			return "", err
+		}
+		if fresh {
+			confirm, err := prompted("Confirm passphrase: ")
+			if err != nil {
+				return "", err
+			}
+			if value != confirm {
+				return "", errors.New("passphrases do not match")
+			}
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "", errors.New("passphrase must not be empty")
+	}
+	return value, nil
+}
+
+func configured() bool {
+	return fd >= 0 || file != "" || os.Getenv(CmdEnv) != ""
+}
+
+func prompted(prompt string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", ErrNoSource
+	}
+
+	// Prompt on stderr so stdout stays clean for piped output such as
+	// 'deecli exec'
+	fmt.Fprint(os.Stderr, prompt)
+	passBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(passBytes)), nil
+}
+
+func readFD(n int) (string, error) {
+	f := os.NewFile(uintptr(n), fmt.Sprintf("fd%d", n))
+	if f == nil {
+		return "", fmt.Errorf("invalid passphrase file descriptor %d", n)
+	}
+
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("error reading passphrase from fd %d: %w", n, err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func readFile(path string) (string, error) {
+	if fi, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && fi.Mode().Perm()&0o077 != 0 {
+		fmt.Fprintf(os.Stderr, "Warning: passphrase file %s is accessible by other users (mode %04o)\n", path, fi.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading passphrase file: %w", err)
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimSpace(line), nil
+}
+
+// runCmd runs the command named by the environment variable env.
+func runCmd(env string) (string, error) {
+	command := os.Getenv(env)
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	var out bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s failed: %w", env, err)
+	}
+
+	line, _, _ := strings.Cut(out.String(), "\n")
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,185 @@
+package askpass
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/term"
+)
+
+// reset clears every source, including the environment, for one test.
+func reset(t *testing.T) {
+	t.Helper()
+	t.Setenv(CmdEnv, "")
+	t.Setenv(BundleCmdEnv, "")
+	Configure(-1, "")
+	t.Cleanup(func() { Configure(-1, "") })
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "passphrase")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name   string
+		source func(t *testing.T)
+		want   string
+	}{
+		{
+			name:   "file, first line trimmed",
+			source: func(t *testing.T) { Configure(-1, writeFile(t, "  from file \nsecond line\n")) },
+			want:   "from file",
+		},
+		{
+			name: "file descriptor",
+			source: func(t *testing.T) {
+				r, w, err := os.Pipe()
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { r.Close() })
+				w.WriteString("from fd\nignored\n")
+				w.Close()
+				Configure(int(r.Fd()), "")
+			},
+			want: "from fd",
+		},
+		{
+			name:   "command",
+			source: func(t *testing.T) { t.Setenv(CmdEnv, "printf 'from cmd\\nignored\\n'") },
+			want:   "from cmd",
+		},
+		{
+			name: "descriptor wins over file",
+			source: func(t *testing.T) {
+				r, w, err := os.Pipe()
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { r.Close() })
+				w.WriteString("from fd\n")
+				w.Close()
+				Configure(int(r.Fd()), writeFile(t, "from file\n"))
+			},
+			want: "from fd",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset(t)
+			tt.source(t)
+			if Interactive() {
+				t.Error("Interactive() = true with a source configured")
+			}
+			got, err := Read("prompt: ")
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Read = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCaches(t *testing.T) {
+	reset(t)
+	path := writeFile(t, "first\n")
+	Configure(-1, path)
+	if got, _ := Read(""); got != "first" {
+		t.Fatalf("Read = %q, want %q", got, "first")
+	}
+	if err := os.WriteFile(path, []byte("second\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := Read(""); got != "first" {
+		t.Errorf("second Read = %q, want the cached %q", got, "first")
+	}
+
+	// Configure starts over
+	Configure(-1, path)
+	if got, _ := Read(""); got != "second" {
+		t.Errorf("Read after Configure = %q, want %q", got, "second")
+	}
+}
+
+func TestReadNew(t *testing.T) {
+	reset(t)
+	Configure(-1, writeFile(t, "\n"))
+	if _, err := ReadNew(""); err == nil || !strings.Contains(err.Error(), "must not be empty") {
+		t.Errorf("ReadNew of an empty passphrase returned %v", err)
+	}
+
+	Configure(-1, writeFile(t, "new passphrase\n"))
+	if got, err := ReadNew(""); err != nil || got != "new passphrase" {
+		t.Errorf("ReadNew = %q, %v; want it accepted without confirmation", got, err)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	t.Run("missing file", func(t *testing.T) {
+		reset(t)
+		Configure(-1, filepath.Join(t.TempDir(), "missing"))
+		if _, err := Read(""); err == nil || !strings.Contains(err.Error(), "error reading passphrase file") {
+			t.Errorf("Read returned %v", err)
+		}
+	})
+
+	t.Run("failing command", func(t *testing.T) {
+		reset(t)
+		t.Setenv(CmdEnv, "exit 3")
+		if _, err := Read(""); err == nil || !strings.Contains(err.Error(), CmdEnv+" failed") {
+			t.Errorf("Read returned %v", err)
+		}
+	})
+
+	t.Run("no source without a terminal", func(t *testing.T) {
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			t.Skip("stdin is a terminal")
+		}
+		reset(t)
+		if !Interactive() {
+			t.Error("Interactive() = false without a source")
+		}
+		if _, err := Read(""); !errors.Is(err, ErrNoSource) {
+			t.Errorf("Read returned %v, want ErrNoSource", err)
+		}
+	})
+}
+
+func TestReadBundle(t *testing.T) {
+	reset(t)
+	Configure(-1, writeFile(t, "store passphrase\n"))
+	if _, err := Read(""); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadBundle(writeFile(t, "bundle passphrase\n"), "", true)
+	if err != nil || got != "bundle passphrase" {
+		t.Errorf("ReadBundle from a file = %q, %v", got, err)
+	}
+
+	t.Setenv(BundleCmdEnv, "echo bundle from cmd")
+	if got, err := ReadBundle("", "", false); err != nil || got != "bundle from cmd" {
+		t.Errorf("ReadBundle from %s = %q, %v", BundleCmdEnv, got, err)
+	}
+
+	if _, err := ReadBundle(writeFile(t, "\n"), "", false); err == nil {
+		t.Error("ReadBundle accepted an empty passphrase")
+	}
+
+	// The bundle passphrase never replaces the store one
+	if got, _ := Read(""); got != "store passphrase" {
+		t.Errorf("Read = %q after ReadBundle, want %q", got, "store passphrase")
+	}
+}
@@ -0,0 +1,178 @@
+// Package clipboard copies values to the system clipboard through the usual
+// command-line tools (wl-copy, xclip, xsel, pbcopy) and clears them again
+// only if the clipboard still holds what was copied.
+package clipboard
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ToolEnv selects a tool by name instead of detecting one.
+const ToolEnv = "DEECLI_CLIPBOARD"
+
+// Clipboard reads and writes the clipboard.
+type Clipboard interface {
+	Copy(text string) error
+	Paste() (string, error)
+	Clear() error
+}
+
+// Tool drives a clipboard through external commands. CopyArgs receives the
+// text on stdin; a nil ClearArgs means copying an empty string.
+type Tool struct {
+	Name      string
+	CopyArgs  []string
+	PasteArgs []string
+	ClearArgs []string
+}
+
+// Tools are tried in order by Default; Wayland comes first since X11 tools
+// also work under XWayland but miss native Wayland clients.
+var Tools = []Tool{
+	{Name: "wl-copy", CopyArgs: []string{"wl-copy"}, PasteArgs: []string{"wl-paste", "--no-newline"}, ClearArgs: []string{"wl-copy", "--clear"}},
+	{Name: "xclip", CopyArgs: []string{"xclip", "-selection", "clipboard", "-in"}, PasteArgs: []string{"xclip", "-selection", "clipboard", "-out"}},
+	{Name: "xsel", CopyArgs: []string{"xsel", "--clipboard", "--input"}, PasteArgs: []string{"xsel", "--clipboard", "--output"}, ClearArgs: []string{"xsel", "--clipboard", "--clear"}},
+	{Name: "pbcopy", CopyArgs: []string{"pbcopy"}, PasteArgs: []string{"pbpaste"}},
+}
+
+// Copy writes text to the clipboard.
+func (t Tool) Copy(text string) error {
+	return t.run(t.CopyArgs, text)
+}
+
+// Paste returns the clipboard contents.
+func (t Tool) Paste() (string, error) {
+	out, err := exec.Command(t.PasteArgs[0], t.PasteArgs[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", t.PasteArgs[0], err)
+	}
+	return string(out), nil
+}
+
+// Clear empties the clipboard.
+func (t Tool) Clear() error {
+	if t.ClearArgs == nil {
+		return t.Copy("")
+	}
+	return t.run(t.ClearArgs, "")
+}
+
+func (t Tool) run(args []string, stdin string) error {
+	// Do not capture stdout or stderr: xclip and wl-copy fork a child that
+	// keeps serving the selection, and Run would wait for it to close a pipe
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	return nil
+}
+
+func (t Tool) available() bool {
+	for _, args := range [][]string{t.CopyArgs, t.PasteArgs} {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	defaultMu sync.Mutex
+	override  Clipboard
+)
+
+// SetDefault overrides the clipboard returned by Default, for example with a
+// Fake in tests. Passing nil restores detection.
+func SetDefault(c Clipboard) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	override = c
+}
+
+// Default returns the clipboard set with SetDefault, the tool named by
+// $DEECLI_CLIPBOARD, or the first tool found on PATH.
+func Default() (Clipboard, error) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if override != nil {
+		return override, nil
+	}
+
+	if name := os.Getenv(ToolEnv); name != "" {
+		for _, t := range Tools {
+			if t.Name == name {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown clipboard tool %q in $%s", name, ToolEnv)
+	}
+	for _, t := range Tools {
+		if t.Name == "wl-copy" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			continue
+		}
+		if t.available() {
+			return t, nil
+		}
+	}
+	return nil, errors.New("no clipboard tool found (install wl-clipboard, xclip or xsel)")
+}
+
+// Fake is an in-memory Clipboard.
+type Fake struct {
+	mu   sync.Mutex
+	text string
+}
+
+func (f *Fake) Copy(text string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.text = text
+	return nil
+}
+
+func (f *Fake) Paste() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.text, nil
+}
+
+func (f *Fake) Clear() error {
+	return f.Copy("")
+}
+
+// Digest identifies text without keeping it, so a process that clears the
+// clipboard later does not need the secret itself.
+func Digest(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// ClearIfUnchanged clears c if it still holds the text whose Digest is
+// digest, and reports whether it did. Anything copied since is left alone.
+func ClearIfUnchanged(c Clipboard, digest string) (bool, error) {
+	current, err := c.Paste()
+	if err != nil {
+		return false, err
+	}
+	if subtle.ConstantTimeCompare([]byte(Digest(current)), []byte(digest)) != 1 {
+		return false, nil
+	}
+	return true, c.Clear()
+}
+
+// ClearAfter waits for d and then clears c as ClearIfUnchanged does.
+func ClearAfter(c Clipboard, digest string, d time.Duration) (bool, error) {
+	time.Sleep(d)
+	return ClearIfUnchanged(c, digest)
+}
@@ -0,0 +1,50 @@
+package clipboard
+
+import "testing"
+
+func TestClearIfUnchanged(t *testing.T) {
+	tests := []struct {
+		name      string
+		copied    string
+		now       string
+		wantClear bool
+		wantLeft  string
+	}{
+		{"still holds the token", "ghp_secret", "ghp_secret", true, ""},
+		{"user copied something else", "ghp_secret", "shopping list", false, "shopping list"},
+		{"already cleared", "ghp_secret", "", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clip := &Fake{}
+			if err := clip.Copy(tt.now); err != nil {
+				t.Fatal(err)
+			}
+			cleared, err := ClearIfUnchanged(clip, Digest(tt.copied))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cleared != tt.wantClear {
+				t.Errorf("cleared = %t, want %t", cleared, tt.wantClear)
+			}
+			if left, _ := clip.Paste(); left != tt.wantLeft {
+				t.Errorf("clipboard holds %q, want %q", left, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestDefaultOverride(t *testing.T) {
+	fake := &Fake{}
+	SetDefault(fake)
+	defer SetDefault(nil)
+
+	c, err := Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != Clipboard(fake) {
+		t.Errorf("Default() = %v, want the Fake set with SetDefault", c)
+	}
+}
@@ -0,0 +1,71 @@
+// Package dockercred implements the docker-credential-helpers protocol, which
+// docker speaks to a "docker-credential-NAME" binary configured as credsStore
+// or in credHelpers of ~/.docker/config.json.
+//
+// The action is the only argument. "store" reads a JSON Credentials object on
+// stdin, "get" and "erase" read a server URL, and "list" reads nothing. Answers
+// and error messages are written to stdout, with exit status 1 on error.
+package dockercred
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Program is the name docker runs for credsStore "deecli".
+const Program = "docker-credential-deecli"
+
+// NamePrefix starts the name of every token holding registry credentials.
+const NamePrefix = "docker/"
+
+// ErrNotFound carries the message docker recognises as "no credentials for
+// this registry", as opposed to a failing helper.
+var ErrNotFound = errors.New("credentials not found in native keychain")
+
+// Credentials are the registry credentials exchanged with docker. A Username
+// of "<token>" marks Secret as an identity token.
+type Credentials struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+// ReadServerURL reads the server URL docker sends to "get" and "erase".
+func ReadServerURL(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	serverURL := strings.TrimSpace(string(data))
+	if serverURL == "" {
+		return "", errors.New("no credentials server URL")
+	}
+	return serverURL, nil
+}
+
+// TokenName returns the token holding the credentials of serverURL, e.g.
+// docker/ghcr.io for https://ghcr.io/. The scheme and trailing slashes are
+// dropped so the variants docker sends for one registry share a token.
+func TokenName(serverURL string) string {
+	host := serverURL
+	if _, rest, ok := strings.Cut(host, "://"); ok {
+		host = rest
+	}
+	return NamePrefix + strings.ToLower(strings.TrimRight(host, "/"))
+}
+
+// Validate checks the credentials sent to "store".
+func (c Credentials) Validate() error {
+	if strings.TrimSpace(c.ServerURL) == "" {
+		return errors.New("no credentials server URL")
+	}
+	if c.Username == "" {
+		return errors.New("no credentials username")
+	}
+	if c.Secret == "" {
+		return fmt.Errorf("no secret for %s", c.ServerURL)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package dockercred
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestReadServerURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"plain", "https://index.docker.io/v1/", "https://index.docker.io/v1/", false},
+		{"trailing newline", "ghcr.io\n", "ghcr.io", false},
+		{"surrounding whitespace", "  registry.example.com:5000 \r\n", "registry.example.com:5000", false},
+		{"empty", "", "", true},
+		{"only whitespace", " \n", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadServerURL(strings.NewReader(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadServerURL error = %v, want error %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ReadServerURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenName(t *testing.T) {
+	tests := []struct {
+		serverURL string
+		want      string
+	}{
+		{"https://index.docker.io/v1/", "docker/index.docker.io/v1"},
+		{"https://ghcr.io/", "docker/ghcr.io"},
+		{"https://ghcr.io", "docker/ghcr.io"},
+		{"ghcr.io", "docker/ghcr.io"},
+		{"GHCR.io", "docker/ghcr.io"},
+		{"http://registry.example.com:5000", "docker/registry.example.com:5000"},
+	}
+	for _, tt := range tests {
+		if got := TokenName(tt.serverURL); got != tt.want {
+			t.Errorf("TokenName(%q) = %q, want %q", tt.serverURL, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeAndValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		wantErr string
+	}{
+		{"login", `{"ServerURL":"https://ghcr.io","Username":"octocat","Secret":"ghp_x"}`, ""},
+		{"identity token", `{"ServerURL":"https://acr.example.io","Username":"<token>","Secret":"eyJ"}`, ""},
+		{"no server URL", `{"ServerURL":" ","Username":"octocat","Secret":"ghp_x"}`, "no credentials server URL"},
+		{"no username", `{"ServerURL":"https://ghcr.io","Secret":"ghp_x"}`, "no credentials username"},
+		{"no secret", `{"ServerURL":"https://ghcr.io","Username":"octocat"}`, "no secret for https://ghcr.io"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Credentials
+			if err := json.Unmarshal([]byte(tt.in), &c); err != nil {
+				t.Fatal(err)
+			}
+			err := c.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate returned %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// Package dotenv reads and writes the KEY=VALUE format of .env files.
+package dotenv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pair is one assignment from a .env file.
+type Pair struct {
+	Key   string
+	Value string
+}
+
+// keyPattern matches the variable names accepted in a .env file.
+var keyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// Parse reads .env content: blank lines and # comments are ignored, an
+// optional "export " prefix is dropped, single-quoted values are literal,
+// double-quoted values may span lines and understand \n, \t, \" and \\, and
+// unquoted values end at " #".
+func Parse(data string) ([]Pair, error) {
+	var pairs []Pair
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !keyPattern.MatchString(key) {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated single quote", lineNo)
+			}
+			value = value[1 : end+1]
+
+		case strings.HasPrefix(value, `"`):
+			// Keep reading lines until the closing quote
+			raw := value[1:]
+			for {
+				if end := closingQuote(raw); end >= 0 {
+					value = unescape(raw[:end])
+					break
+				}
+				i++
+				if i >= len(lines) {
+					return nil, fmt.Errorf("line %d: unterminated double quote", lineNo)
+				}
+				raw += "\n" + lines[i]
+			}
+
+		default:
+			if idx := strings.Index(value, " #"); idx >= 0 {
+				value = strings.TrimSpace(value[:idx])
+			}
+		}
+
+		pairs = append(pairs, Pair{Key: key, Value: value})
+	}
+	return pairs, nil
+}
+
+// Quote renders value for the right-hand side of a .env assignment, quoting
+// only when needed.
+func Quote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n\r\"'#$\\`=") {
+		return value
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "$", `\$`, "`", "\\`")
+	return `"` + r.Replace(value) + `"`
+}
+
+// closingQuote returns the index of the first unescaped double quote in s.
+func closingQuote(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package dotenv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Pair
+	}{
+		{
+			name: "plain pairs, comments and blank lines",
+			data: "# database\nDB_HOST=localhost\n\nDB_PORT = 5432\n",
+			want: []Pair{{"DB_HOST", "localhost"}, {"DB_PORT", "5432"}},
+		},
+		{
+			name: "export prefix",
+			data: "export API_KEY=abc123",
+			want: []Pair{{"API_KEY", "abc123"}},
+		},
+		{
+			name: "trailing comment on unquoted value",
+			data: "TOKEN=abc # rotated monthly",
+			want: []Pair{{"TOKEN", "abc"}},
+		},
+		{
+			name: "hash without a space is part of the value",
+			data: "COLOR=#ff0000",
+			want: []Pair{{"COLOR", "#ff0000"}},
+		},
+		{
+			name: "single quotes are literal",
+			data: `PASSWORD='p@ss \n #word'`,
+			want: []Pair{{"PASSWORD", `p@ss \n #word`}},
+		},
+		{
+			name: "double quotes understand escapes",
+			data: `GREETING="line one\nsaid \"hi\"\ttab \\ end"`,
+			want: []Pair{{"GREETING", "line one\nsaid \"hi\"\ttab \\ end"}},
+		},
+		{
+			name: "double quotes span lines",
+			data: "KEY=\"-----BEGIN KEY-----\nabc\n-----END KEY-----\"\nNEXT=1",
+			want: []Pair{{"KEY", "-----BEGIN KEY-----\nabc\n-----END KEY-----"}, {"NEXT", "1"}},
+		},
+		{
+			name: "windows line endings",
+			data: "A=1\r\nB=2\r\n",
+			want: []Pair{{"A", "1"}, {"B", "2"}},
+		},
+		{
+			name: "empty value",
+			data: "EMPTY=",
+			want: []Pair{{"EMPTY", ""}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.data)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"missing equals", "A=1\nJUSTAKEY", "line 2: expected KEY=VALUE"},
+		{"invalid key", "1ABC=x", "line 1: expected KEY=VALUE"},
+		{"unterminated single quote", "A='abc", "line 1: unterminated single quote"},
+		{"unterminated double quote", "A=1\nB=\"abc\nC=2", "line 2: unterminated double quote"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse returned %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQuoteRoundTrip(t *testing.T) {
+	values := []string{
+		"plain",
+		"",
+		"with spaces",
+		"  padded  ",
+		"multi\nline\r\nvalue",
+		"tab\there",
+		`back\slash`,
+		`"double" and 'single'`,
+		"$HOME and `cmd`",
+		"a # comment lookalike",
+		"key=value",
+		"#leading",
+	}
+
+	for _, value := range values {
+		t.Run(value, func(t *testing.T) {
+			line := "KEY=" + Quote(value)
+			got, err := Parse(line)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", line, err)
+			}
+			if len(got) != 1 || got[0].Value != value {
+				t.Errorf("Parse(%q) = %q, want value %q", line, got, value)
+			}
+		})
+	}
+
+	if got := Quote("abc123"); got != "abc123" {
+		t.Errorf("Quote(%q) = %q, want it unquoted", "abc123", got)
+	}
+}
@@ -0,0 +1,449 @@
+// Package envelope implements the self-describing ciphertext format used for
+// entries in ~/.secrets.json.
+//
+// An envelope is a single string of the form
+//
+//	$deecli$v=3$scrypt$n=32768,r=8,p=1$<salt>$<nonce||ciphertext>
+//
+// where salt and nonce||ciphertext are unpadded standard base64. Entries
+// sealed directly under the vault data-encryption key use the "vault" KDF and
+// carry no parameters or salt. Version 3 envelopes authenticate the name of
+// the entry they are stored under as associated data, so a ciphertext moved
+// to another name no longer opens; version 2 envelopes open under any name.
+// Entries written before the format was versioned are bare
+// base64(salt||nonce||ciphertext) with fixed scrypt parameters; Parse reports
+// those as version 1.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// Version is the envelope version written by Seal.
+	Version = 3
+
+	// UnboundVersion identifies envelopes that do not authenticate the entry
+	// name.
+	UnboundVersion = 2
+
+	// LegacyVersion identifies bare base64 entries written by older releases.
+	LegacyVersion = 1
+
+	prefix = "$deecli$"
+
+	saltSize  = 16
+	nonceSize = 12
+	keySize   = 32
+)
+
+// KDF identifies the key derivation function used to turn a passphrase into
+// an AES-256 key.
+type KDF string
+
+const (
+	KDFScrypt   KDF = "scrypt"
+	KDFArgon2id KDF = "argon2id"
+
+	// KDFVault marks entries sealed with the vault data-encryption key
+	// rather than a key derived from a passphrase.
+	KDFVault KDF = "vault"
+)
+
+// ErrVaultSealed is returned when a passphrase is used to open an entry that
+// is sealed with the vault key.
+var ErrVaultSealed = errors.New("entry is sealed with the vault key")
+
+// ErrAuthFailed is returned when the ciphertext does not authenticate: the
+// passphrase or key is wrong, the entry was stored under another name, or the
+// data was altered. AES-GCM cannot tell these apart.
+var ErrAuthFailed = errors.New("cipher: message authentication failed")
+
+// Params holds the KDF identifier and its cost parameters. Only the fields
+// belonging to the selected KDF are meaningful.
+type Params struct {
+	KDF KDF
+
+	// scrypt
+	N, R, P int
+
+	// argon2id
+	Time    uint32
+	Memory  uint32 // KiB
+	Threads uint8
+}
+
+// DefaultParams are used for newly encrypted entries.
+var DefaultParams = ScryptParams
+
+// ScryptParams match the cost used by every release so far.
+var ScryptParams = Params{KDF: KDFScrypt, N: 32768, R: 8, P: 1}
+
+// Argon2idParams follow the RFC 9106 second recommended option.
+var Argon2idParams = Params{KDF: KDFArgon2id, Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// Upper bounds on KDF costs accepted from an envelope, 16 times the
+// defaults. Parameters come from the file being opened, so without them a
+// crafted entry or bundle could make key derivation exhaust memory or run
+// for hours.
+const (
+	maxScryptMemory  = 16 * 128 * 32768 * 8 // bytes: 128*N*r
+	maxScryptP       = 16
+	maxArgon2Memory  = 16 * 64 * 1024 // KiB
+	maxArgon2Time    = 16 * 3
+	maxArgon2Threads = 16 * 4
+)
+
+// ParamsFor returns the default parameters for the named KDF.
+func ParamsFor(name string) (Params, error) {
+	switch KDF(name) {
+	case KDFScrypt:
+		return ScryptParams, nil
+	case KDFArgon2id:
+		return Argon2idParams, nil
+	}
+	return Params{}, fmt.Errorf("unknown KDF %q (supported: scrypt, argon2id)", name)
+}
+
+// Envelope is a parsed ciphertext entry.
+type Envelope struct {
+	Version    int
+	Params     Params
+	Salt       []byte
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// Seal encrypts plaintext under a key derived from passphrase with p and
+// returns the encoded envelope. It is meant for values that are not stored
+// under an entry name; see SealFor.
+func Seal(plaintext []byte, passphrase string, p Params) (string, error) {
+	return SealFor("", plaintext, passphrase, p)
+}
+
+// SealFor is like Seal but binds the envelope to name, the entry it will be
+// stored under. It only opens through OpenFor with the same name.
+func SealFor(name string, plaintext []byte, passphrase string, p Params) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", err
+	}
+
+	key, err := p.deriveKey(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+
+	aesGCM, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aesGCM.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	env := &Envelope{
+		Version:    Version,
+		Params:     p,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: aesGCM.Seal(nil, nonce, plaintext, associatedData(Version, name)),
+	}
+	return env.String(), nil
+}
+
+// SealWithKey encrypts plaintext directly under a 32-byte key, such as the
+// vault data-encryption key.
+func SealWithKey(plaintext, key []byte) (string, error) {
+	return SealWithKeyFor("", plaintext, key)
+}
+
+// SealWithKeyFor is like SealWithKey but binds the envelope to name.
+func SealWithKeyFor(name string, plaintext, key []byte) (string, error) {
+	aesGCM, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aesGCM.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	env := &Envelope{
+		Version:    Version,
+		Params:     Params{KDF: KDFVault},
+		Nonce:      nonce,
+		Ciphertext: aesGCM.Seal(nil, nonce, plaintext, associatedData(Version, name)),
+	}
+	return env.String(), nil
+}
+
+// Open parses encoded, which may be in either the current or the legacy
+// format, and decrypts it with passphrase.
+func Open(encoded, passphrase string) ([]byte, error) {
+	env, err := Parse(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return env.Open(passphrase)
+}
+
+// Open decrypts the envelope with passphrase.
+func (e *Envelope) Open(passphrase string) ([]byte, error) {
+	return e.OpenFor("", passphrase)
+}
+
+// OpenFor decrypts an envelope stored under name with passphrase.
+func (e *Envelope) OpenFor(name, passphrase string) ([]byte, error) {
+	if e.Params.KDF == KDFVault {
+		return nil, ErrVaultSealed
+	}
+
+	key, err := e.Params.deriveKey(passphrase, e.Salt)
+	if err != nil {
+		return nil, err
+	}
+	return e.open(name, key)
+}
+
+// OpenWithKey decrypts an envelope produced by SealWithKey.
+func (e *Envelope) OpenWithKey(key []byte) ([]byte, error) {
+	return e.OpenWithKeyFor("", key)
+}
+
+// OpenWithKeyFor decrypts an envelope produced by SealWithKeyFor for name.
+func (e *Envelope) OpenWithKeyFor(name string, key []byte) ([]byte, error) {
+	if e.Params.KDF != KDFVault {
+		return nil, fmt.Errorf("entry is sealed with a %s passphrase, not the vault key", e.Params.KDF)
+	}
+	return e.open(name, key)
+}
+
+// VaultSealed reports whether the envelope is sealed with the vault key.
+func (e *Envelope) VaultSealed() bool {
+	return e.Params.KDF == KDFVault
+}
+
+// Bound reports whether the envelope authenticates the entry name.
+func (e *Envelope) Bound() bool {
+	return e.Version >= Version
+}
+
+func (e *Envelope) open(name string, key []byte) ([]byte, error) {
+	aesGCM, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Nonce) != aesGCM.NonceSize() {
+		return nil, errors.New("invalid nonce length")
+	}
+
+	plaintext, err := aesGCM.Open(nil, e.Nonce, e.Ciphertext, associatedData(e.Version, name))
+	if err != nil {
+		return nil, ErrAuthFailed
+	}
+	return plaintext, nil
+}
+
+// associatedData returns the data authenticated alongside the ciphertext of
+// an envelope of the given version stored under name.
+func associatedData(version int, name string) []byte {
+	if version < Version {
+		return nil
+	}
+	return []byte("deecli-entry\x00" + name)
+}
+
+// IsLegacy reports whether encoded uses the unversioned format.
+func IsLegacy(encoded string) bool {
+	return !strings.HasPrefix(encoded, prefix)
+}
+
+// Parse decodes an envelope without decrypting it.
+func Parse(encoded string) (*Envelope, error) {
+	if IsLegacy(encoded) {
+		return parseLegacy(encoded)
+	}
+
+	// "", "deecli", "v=3", kdf, params, salt, payload
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 7 {
+		return nil, errors.New("malformed envelope")
+	}
+
+	version, err := strconv.Atoi(strings.TrimPrefix(parts[2], "v="))
+	if err != nil || !strings.HasPrefix(parts[2], "v=") {
+		return nil, fmt.Errorf("malformed envelope version %q", parts[2])
+	}
+	if version != Version && version != UnboundVersion {
+		return nil, fmt.Errorf("unsupported envelope version %d", version)
+	}
+
+	params, err := parseParams(KDF(parts[3]), parts[4])
+	if err != nil {
+		return nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, fmt.Errorf("malformed envelope salt: %w", err)
+	}
+
+	payload, err := base64.RawStdEncoding.DecodeString(parts[6])
+	if err != nil {
+		return nil, fmt.Errorf("malformed envelope payload: %w", err)
+	}
+	if len(payload) < nonceSize {
+		return nil, errors.New("encrypted data too short")
+	}
+
+	return &Envelope{
+		Version:    version,
+		Params:     params,
+		Salt:       salt,
+		Nonce:      payload[:nonceSize],
+		Ciphertext: payload[nonceSize:],
+	}, nil
+}
+
+// String encodes the envelope in the versioned format.
+func (e *Envelope) String() string {
+	payload := make([]byte, 0, len(e.Nonce)+len(e.Ciphertext))
+	payload = append(payload, e.Nonce...)
+	payload = append(payload, e.Ciphertext...)
+
+	return strings.Join([]string{
+		prefix + "v=" + strconv.Itoa(e.Version),
+		string(e.Params.KDF),
+		e.Params.encode(),
+		base64.RawStdEncoding.EncodeToString(e.Salt),
+		base64.RawStdEncoding.EncodeToString(payload),
+	}, "$")
+}
+
+func parseLegacy(encoded string) (*Envelope, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < saltSize+nonceSize {
+		return nil, errors.New("encrypted data too short")
+	}
+
+	return &Envelope{
+		Version:    LegacyVersion,
+		Params:     ScryptParams,
+		Salt:       data[:saltSize],
+		Nonce:      data[saltSize : saltSize+nonceSize],
+		Ciphertext: data[saltSize+nonceSize:],
+	}, nil
+}
+
+// String describes the KDF and its parameters, e.g. "scrypt n=32768,r=8,p=1".
+func (p Params) String() string {
+	if p.KDF == KDFVault {
+		return "vault key"
+	}
+	return string(p.KDF) + " " + p.encode()
+}
+
+func (p Params) encode() string {
+	switch p.KDF {
+	case KDFArgon2id:
+		return fmt.Sprintf("t=%d,m=%d,p=%d", p.Time, p.Memory, p.Threads)
+	case KDFVault:
+		return ""
+	default:
+		return fmt.Sprintf("n=%d,r=%d,p=%d", p.N, p.R, p.P)
+	}
+}
+
+func parseParams(kdf KDF, s string) (Params, error) {
+	if kdf == KDFVault {
+		return Params{KDF: KDFVault}, nil
+	}
+
+	values := map[string]uint64{}
+	for _, kv := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return Params{}, fmt.Errorf("malformed KDF parameter %q", kv)
+		}
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return Params{}, fmt.Errorf("malformed KDF parameter %q", kv)
+		}
+		values[k] = n
+	}
+
+	p := Params{KDF: kdf}
+	switch kdf {
+	case KDFScrypt:
+		p.N, p.R, p.P = int(values["n"]), int(values["r"]), int(values["p"])
+	case KDFArgon2id:
+		if values["p"] > 255 {
+			return Params{}, fmt.Errorf("argon2id parallelism %d out of range", values["p"])
+		}
+		p.Time, p.Memory, p.Threads = uint32(values["t"]), uint32(values["m"]), uint8(values["p"])
+	default:
+		return Params{}, fmt.Errorf("unsupported KDF %q", kdf)
+	}
+	return p, p.validate()
+}
+
+func (p Params) validate() error {
+	switch p.KDF {
+	case KDFScrypt:
+		if p.N < 2 || p.N&(p.N-1) != 0 || p.R < 1 || p.P < 1 {
+			return fmt.Errorf("invalid scrypt parameters n=%d,r=%d,p=%d", p.N, p.R, p.P)
+		}
+		if uint64(p.N)*uint64(p.R) > maxScryptMemory/128 || p.P > maxScryptP {
+			return fmt.Errorf("scrypt parameters n=%d,r=%d,p=%d exceed the supported maximum", p.N, p.R, p.P)
+		}
+	case KDFArgon2id:
+		if p.Time < 1 || p.Memory < 8*uint32(p.Threads) || p.Threads < 1 {
+			return fmt.Errorf("invalid argon2id parameters t=%d,m=%d,p=%d", p.Time, p.Memory, p.Threads)
+		}
+		if p.Time > maxArgon2Time || p.Memory > maxArgon2Memory || p.Threads > maxArgon2Threads {
+			return fmt.Errorf("argon2id parameters t=%d,m=%d,p=%d exceed the supported maximum", p.Time, p.Memory, p.Threads)
+		}
+	default:
+		return fmt.Errorf("unsupported KDF %q", p.KDF)
+	}
+	return nil
+}
+
+func (p Params) deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	switch p.KDF {
+	case KDFArgon2id:
+		return argon2.IDKey([]byte(passphrase), salt, p.Time, p.Memory, p.Threads, keySize), nil
+	default:
+		return scrypt.Key([]byte(passphrase), salt, p.N, p.R, p.P, keySize)
+	}
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,239 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Cheap parameters keep the tests fast; the format does not depend on cost.
+var (
+	testScrypt   = Params{KDF: KDFScrypt, N: 1024, R: 8, P: 1}
+	testArgon2id = Params{KDF: KDFArgon2id, Time: 1, Memory: 64, Threads: 1}
+)
+
+// sealUnbound builds an envelope of an older version the way the releases
+// that wrote it did: no associated data, and for version 1 the bare
+// base64(salt||nonce||ciphertext) form with the fixed scrypt parameters.
+func sealUnbound(t *testing.T, version int, plaintext []byte, passphrase string, p Params) string {
+	t.Helper()
+	salt := make([]byte, saltSize)
+	nonce := make([]byte, nonceSize)
+	rand.Read(salt)
+	rand.Read(nonce)
+	key, err := p.deriveKey(passphrase, salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aesGCM, err := newGCM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := aesGCM.Seal(nil, nonce, plaintext, nil)
+
+	if version == LegacyVersion {
+		return base64.StdEncoding.EncodeToString(append(append(salt, nonce...), ciphertext...))
+	}
+	env := &Envelope{Version: version, Params: p, Salt: salt, Nonce: nonce, Ciphertext: ciphertext}
+	return env.String()
+}
+
+func TestRoundTrip(t *testing.T) {
+	plaintext := []byte("ghp_0123456789abcdef")
+
+	tests := []struct {
+		name        string
+		seal        func(t *testing.T) string
+		wantVersion int
+		wantBound   bool
+	}{
+		{
+			name:        "v1 legacy",
+			seal:        func(t *testing.T) string { return sealUnbound(t, LegacyVersion, plaintext, "pw", ScryptParams) },
+			wantVersion: LegacyVersion,
+		},
+		{
+			name:        "v2 scrypt",
+			seal:        func(t *testing.T) string { return sealUnbound(t, UnboundVersion, plaintext, "pw", testScrypt) },
+			wantVersion: UnboundVersion,
+		},
+		{
+			name:        "v2 argon2id",
+			seal:        func(t *testing.T) string { return sealUnbound(t, UnboundVersion, plaintext, "pw", testArgon2id) },
+			wantVersion: UnboundVersion,
+		},
+		{
+			name: "v3 scrypt",
+			seal: func(t *testing.T) string {
+				s, err := SealFor("github", plaintext, "pw", testScrypt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return s
+			},
+			wantVersion: Version,
+			wantBound:   true,
+		},
+		{
+			name: "v3 argon2id",
+			seal: func(t *testing.T) string {
+				s, err := SealFor("github", plaintext, "pw", testArgon2id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return s
+			},
+			wantVersion: Version,
+			wantBound:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := tt.seal(t)
+			env, err := Parse(encoded)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if env.Version != tt.wantVersion {
+				t.Errorf("version %d, want %d", env.Version, tt.wantVersion)
+			}
+			if env.Bound() != tt.wantBound {
+				t.Errorf("Bound() = %t, want %t", env.Bound(), tt.wantBound)
+			}
+			if env.Version != LegacyVersion && env.String() != encoded {
+				t.Errorf("String() = %q, want %q", env.String(), encoded)
+			}
+
+			got, err := env.OpenFor("github", "pw")
+			if err != nil {
+				t.Fatalf("OpenFor: %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("opened %q, want %q", got, plaintext)
+			}
+			if _, err := env.OpenFor("github", "wrong"); !errors.Is(err, ErrAuthFailed) {
+				t.Errorf("wrong passphrase returned %v, want ErrAuthFailed", err)
+			}
+		})
+	}
+}
+
+func TestNameBinding(t *testing.T) {
+	key := make([]byte, keySize)
+	rand.Read(key)
+
+	passphraseSealed, err := SealFor("github", []byte("token"), "pw", testScrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keySealed, err := SealWithKeyFor("github", []byte("token"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unbound := sealUnbound(t, UnboundVersion, []byte("token"), "pw", testScrypt)
+
+	tests := []struct {
+		name    string
+		encoded string
+		open    func(*Envelope, string) ([]byte, error)
+		as      string
+		wantErr error
+	}{
+		{"passphrase, same name", passphraseSealed, openWith("pw", nil), "github", nil},
+		{"passphrase, other name", passphraseSealed, openWith("pw", nil), "stripe", ErrAuthFailed},
+		{"passphrase, no name", passphraseSealed, openWith("pw", nil), "", ErrAuthFailed},
+		{"vault key, same name", keySealed, openWith("", key), "github", nil},
+		{"vault key, other name", keySealed, openWith("", key), "stripe", ErrAuthFailed},
+		{"v2 opens under any name", unbound, openWith("pw", nil), "stripe", nil},
+		{"vault entry with a passphrase", keySealed, openWith("pw", nil), "github", ErrVaultSealed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, err := Parse(tt.encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tt.open(env, tt.as)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("open as %q returned %v, want %v", tt.as, err, tt.wantErr)
+			}
+			if err == nil && string(got) != "token" {
+				t.Errorf("opened %q, want %q", got, "token")
+			}
+		})
+	}
+}
+
+// openWith opens an envelope with the vault key when key is set, and with
+// passphrase otherwise.
+func openWith(passphrase string, key []byte) func(*Envelope, string) ([]byte, error) {
+	return func(e *Envelope, name string) ([]byte, error) {
+		if key != nil {
+			return e.OpenWithKeyFor(name, key)
+		}
+		return e.OpenFor(name, passphrase)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	valid, err := SealFor("github", []byte("token"), "pw", testScrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(valid, "$")
+	with := func(i int, v string) string {
+		p := append([]string(nil), parts...)
+		p[i] = v
+		return strings.Join(p, "$")
+	}
+	argon2id := func(params string) string {
+		return strings.Join([]string{parts[0], parts[1], parts[2], "argon2id", params, parts[5], parts[6]}, "$")
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+		wantErr string
+	}{
+		{"too few fields", "$deecli$v=3$scrypt$n=1024,r=8,p=1$salt", "malformed envelope"},
+		{"unknown version", with(2, "v=9"), "unsupported envelope version 9"},
+		{"malformed version", with(2, "x=3"), "malformed envelope version"},
+		{"unknown KDF", with(3, "bcrypt"), "unsupported KDF"},
+		{"scrypt n not a power of two", with(4, "n=1000,r=8,p=1"), "invalid scrypt parameters"},
+		{"scrypt memory too large", with(4, "n=1073741824,r=8,p=1"), "exceed the supported maximum"},
+		{"scrypt p too large", with(4, "n=1024,r=8,p=4096"), "exceed the supported maximum"},
+		{"argon2id threads out of range", argon2id("t=1,m=64,p=300"), "out of range"},
+		{"argon2id over the limits", argon2id("t=1,m=4294967295,p=1"), "exceed the supported maximum"},
+		{"short payload", with(6, "AAAA"), "encrypted data too short"},
+		{"short legacy entry", base64.StdEncoding.EncodeToString([]byte("short")), "encrypted data too short"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.encoded)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse returned %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTamperedCiphertext(t *testing.T) {
+	encoded, err := SealFor("github", []byte("token"), "pw", testScrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := Parse(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Ciphertext[0] ^= 1
+	if _, err := env.OpenFor("github", "pw"); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("tampered ciphertext returned %v, want ErrAuthFailed", err)
+	}
+}
@@ -0,0 +1,131 @@
+// Package factors turns the unlock factors required by the store settings (a
+// passphrase, a keyfile, or both) into the secret fed to the key derivation
+// function. With the passphrase factor alone the secret is the passphrase
+// itself, so existing entries keep opening unchanged.
+package factors
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/deeragoo/deecli/internal/askpass"
+	"github.com/deeragoo/deecli/internal/policy"
+	"github.com/deeragoo/deecli/internal/settings"
+)
+
+// KeyfileEnv overrides the keyfile path from the settings, e.g. when the USB
+// stick is mounted somewhere else.
+const KeyfileEnv = "DEECLI_KEYFILE"
+
+// KeyfileSize is the number of random bytes written by CreateKeyfile, and the
+// minimum size accepted for a keyfile.
+const KeyfileSize = 64
+
+// Read prompts for the factors required by the current settings and returns
+// the combined secret.
+func Read(prompt string) (string, error) {
+	s, err := settings.Load()
+	if err != nil {
+		return "", err
+	}
+	return read(s, prompt, false)
+}
+
+// ReadNew is like Read but confirms a newly chosen passphrase and checks it
+// against the passphrase policy.
+func ReadNew(prompt string) (string, error) {
+	s, err := settings.Load()
+	if err != nil {
+		return "", err
+	}
+	return read(s, prompt, true)
+}
+
+// ReadFor is like ReadNew for settings that are not saved yet.
+func ReadFor(s settings.Settings, prompt string) (string, error) {
+	return read(s, prompt, true)
+}
+
+func read(s settings.Settings, prompt string, fresh bool) (string, error) {
+	mode := s.FactorMode()
+
+	var passphrase string
+	if mode != settings.FactorKeyfile {
+		var err error
+		if fresh {
+			passphrase, err = askpass.ReadNew(prompt)
+			if err == nil {
+				err = policy.Enforce(passphrase)
+			}
+		} else {
+			passphrase, err = askpass.Read(prompt)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if mode == settings.FactorPassphrase {
+		return passphrase, nil
+	}
+
+	digest, err := keyfileDigest(s)
+	if err != nil {
+		return "", err
+	}
+	if mode == settings.FactorKeyfile {
+		return "keyfile:" + digest, nil
+	}
+	if passphrase == "" {
+		// Leave empty answers empty so callers can treat them as "skip"
+		return "", nil
+	}
+	combined := sha256.Sum256([]byte("deecli-factors-v1\x00" + passphrase + "\x00" + digest))
+	return "passphrase+keyfile:" + hex.EncodeToString(combined[:]), nil
+}
+
+// KeyfilePath returns the keyfile in effect for s.
+func KeyfilePath(s settings.Settings) string {
+	if path := os.Getenv(KeyfileEnv); path != "" {
+		return path
+	}
+	return s.Keyfile
+}
+
+func keyfileDigest(s settings.Settings) (string, error) {
+	path := KeyfilePath(s)
+	if path == "" {
+		return "", errors.New("the store requires a keyfile but none is configured (set " + KeyfileEnv + ")")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading keyfile: %w", err)
+	}
+	if len(data) < KeyfileSize {
+		return "", fmt.Errorf("keyfile %s is too short (need at least %d bytes)", path, KeyfileSize)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CreateKeyfile writes KeyfileSize random bytes to path with owner-only
+// permissions. It refuses to replace an existing file.
+func CreateKeyfile(path string) error {
+	key := make([]byte, KeyfileSize)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o400)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(key); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,134 @@
+package factors
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deeragoo/deecli/internal/askpass"
+	"github.com/deeragoo/deecli/internal/settings"
+)
+
+// usePassphrase makes askpass return passphrase without prompting.
+func usePassphrase(t *testing.T, passphrase string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "passphrase")
+	if err := os.WriteFile(path, []byte(passphrase+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	askpass.Configure(-1, path)
+	t.Cleanup(func() { askpass.Configure(-1, "") })
+}
+
+func newKeyfile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keyfile")
+	if err := CreateKeyfile(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRead(t *testing.T) {
+	t.Setenv(KeyfileEnv, "")
+	keyfile, other := newKeyfile(t), newKeyfile(t)
+
+	secret := func(t *testing.T, s settings.Settings, passphrase string) string {
+		t.Helper()
+		usePassphrase(t, passphrase)
+		got, err := read(s, "", false)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		return got
+	}
+
+	t.Run("passphrase alone is the secret", func(t *testing.T) {
+		if got := secret(t, settings.Settings{}, "pw"); got != "pw" {
+			t.Errorf("secret %q, want %q", got, "pw")
+		}
+	})
+
+	t.Run("keyfile ignores the passphrase", func(t *testing.T) {
+		s := settings.Settings{Factors: settings.FactorKeyfile, Keyfile: keyfile}
+		a, b := secret(t, s, "pw"), secret(t, s, "other")
+		if a != b || !strings.HasPrefix(a, "keyfile:") {
+			t.Errorf("secrets %q and %q, want the same keyfile secret", a, b)
+		}
+	})
+
+	t.Run("passphrase and keyfile both count", func(t *testing.T) {
+		s := settings.Settings{Factors: settings.FactorPassphraseKeyfile, Keyfile: keyfile}
+		base := secret(t, s, "pw")
+		if base == "pw" || !strings.HasPrefix(base, "passphrase+keyfile:") {
+			t.Fatalf("secret %q does not combine the factors", base)
+		}
+		if secret(t, s, "other") == base {
+			t.Error("a different passphrase gave the same secret")
+		}
+		s.Keyfile = other
+		if secret(t, s, "pw") == base {
+			t.Error("a different keyfile gave the same secret")
+		}
+	})
+
+	t.Run("environment overrides the keyfile", func(t *testing.T) {
+		s := settings.Settings{Factors: settings.FactorKeyfile, Keyfile: keyfile}
+		want := secret(t, s, "")
+		t.Setenv(KeyfileEnv, keyfile)
+		s.Keyfile = "/nonexistent"
+		if got := secret(t, s, ""); got != want {
+			t.Errorf("secret %q, want %q", got, want)
+		}
+	})
+
+	t.Run("empty answer stays empty", func(t *testing.T) {
+		s := settings.Settings{Factors: settings.FactorPassphraseKeyfile, Keyfile: keyfile}
+		if got := secret(t, s, ""); got != "" {
+			t.Errorf("secret %q, want empty", got)
+		}
+	})
+}
+
+func TestReadKeyfileErrors(t *testing.T) {
+	t.Setenv(KeyfileEnv, "")
+	short := filepath.Join(t.TempDir(), "short")
+	if err := os.WriteFile(short, make([]byte, KeyfileSize-1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		keyfile string
+		wantErr string
+	}{
+		{"none configured", "", "none is configured"},
+		{"missing", filepath.Join(t.TempDir(), "missing"), "error reading keyfile"},
+		{"too short", short, "too short"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usePassphrase(t, "pw")
+			s := settings.Settings{Factors: settings.FactorKeyfile, Keyfile: tt.keyfile}
+			if _, err := read(s, "", false); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("read returned %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateKeyfile(t *testing.T) {
+	path := newKeyfile(t)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != KeyfileSize || info.Mode().Perm() != 0o400 {
+		t.Errorf("keyfile has size %d and mode %v, want %d and 0400", info.Size(), info.Mode().Perm(), KeyfileSize)
+	}
+	if err := CreateKeyfile(path); err == nil {
+		t.Error("CreateKeyfile replaced an existing file")
+	}
+}
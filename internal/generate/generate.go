@@ -0,0 +1,123 @@
+// Package generate creates random secret values (API keys, webhook secrets,
+// passwords and diceware passphrases) from crypto/rand.
+package generate
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Charset values accepted by Generate.
+const (
+	CharsetURLSafe = "urlsafe"
+	CharsetHex     = "hex"
+	CharsetAlnum   = "alnum"
+	CharsetWords   = "words"
+)
+
+// Charsets lists the accepted charsets, for help texts.
+var Charsets = []string{CharsetURLSafe, CharsetHex, CharsetAlnum, CharsetWords}
+
+// DefaultLength is the default number of characters, and DefaultWords the
+// default number of words for CharsetWords.
+const (
+	DefaultLength = 40
+	DefaultWords  = 6
+)
+
+const (
+	alnum   = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	urlsafe = alnum + "-_"
+	hex     = "0123456789abcdef"
+)
+
+//go:embed eff_large_wordlist.txt
+var wordList string
+
+// words holds the diceware words in dice-roll order.
+var words = func() []string {
+	var list []string
+	for _, line := range strings.Split(wordList, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		_, word, _ := strings.Cut(line, "\t")
+		list = append(list, word)
+	}
+	return list
+}()
+
+// Generate returns a random value of length characters drawn uniformly from
+// charset, or of length words joined by "-" for CharsetWords.
+func Generate(charset string, length int) (string, error) {
+	if length < 1 {
+		return "", errors.New("length must be at least 1")
+	}
+
+	if charset == CharsetWords {
+		picked := make([]string, length)
+		for i := range picked {
+			n, err := pick(len(words))
+			if err != nil {
+				return "", err
+			}
+			picked[i] = words[n]
+		}
+		return strings.Join(picked, "-"), nil
+	}
+
+	alphabet, err := alphabetFor(charset)
+	if err != nil {
+		return "", err
+	}
+	out := make([]byte, length)
+	for i := range out {
+		n, err := pick(len(alphabet))
+		if err != nil {
+			return "", err
+		}
+		out[i] = alphabet[n]
+	}
+	return string(out), nil
+}
+
+// Entropy returns the strength in bits of a value produced by Generate with
+// the same arguments.
+func Entropy(charset string, length int) float64 {
+	size := len(words)
+	if charset != CharsetWords {
+		alphabet, err := alphabetFor(charset)
+		if err != nil {
+			return 0
+		}
+		size = len(alphabet)
+	}
+	return float64(length) * math.Log2(float64(size))
+}
+
+func alphabetFor(charset string) (string, error) {
+	switch charset {
+	case CharsetURLSafe:
+		return urlsafe, nil
+	case CharsetHex:
+		return hex, nil
+	case CharsetAlnum:
+		return alnum, nil
+	}
+	return "", fmt.Errorf("unknown charset %q (use %s)", charset, strings.Join(Charsets, ", "))
+}
+
+// pick returns a uniformly random index below n.
+func pick(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
@@ -0,0 +1,108 @@
+package generate
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		charset  string
+		length   int
+		alphabet string
+	}{
+		{CharsetURLSafe, DefaultLength, urlsafe},
+		{CharsetHex, 64, hex},
+		{CharsetAlnum, 1, alnum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.charset, func(t *testing.T) {
+			value, err := Generate(tt.charset, tt.length)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(value) != tt.length {
+				t.Errorf("length %d, want %d", len(value), tt.length)
+			}
+			if i := strings.IndexFunc(value, func(r rune) bool { return !strings.ContainsRune(tt.alphabet, r) }); i >= 0 {
+				t.Errorf("%q has %q outside the %s alphabet", value, value[i], tt.charset)
+			}
+			other, _ := Generate(tt.charset, tt.length)
+			if tt.length > 1 && other == value {
+				t.Errorf("two values in a row were both %q", value)
+			}
+		})
+	}
+}
+
+func TestGenerateWords(t *testing.T) {
+	if len(words) != 7776 {
+		t.Fatalf("word list has %d words, want 7776", len(words))
+	}
+	known := map[string]bool{}
+	for _, w := range words {
+		known[w] = true
+	}
+
+	value, err := Generate(CharsetWords, DefaultWords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	picked := strings.Split(value, "-")
+	if len(picked) != DefaultWords {
+		t.Fatalf("%q has %d words, want %d", value, len(picked), DefaultWords)
+	}
+	for _, w := range picked {
+		if !known[w] {
+			t.Errorf("%q is not on the word list", w)
+		}
+	}
+}
+
+func TestGenerateRejects(t *testing.T) {
+	if _, err := Generate(CharsetHex, 0); err == nil {
+		t.Error("length 0 accepted")
+	}
+	if _, err := Generate("base32", 10); err == nil || !strings.Contains(err.Error(), "unknown charset") {
+		t.Errorf("unknown charset returned %v", err)
+	}
+}
+
+func TestUniform(t *testing.T) {
+	// 16 hex digits, 64000 draws: each should come up about 4000 times.
+	counts := map[rune]int{}
+	value, err := Generate(CharsetHex, 64000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range value {
+		counts[r]++
+	}
+	for _, r := range hex {
+		if n := counts[r]; n < 3500 || n > 4500 {
+			t.Errorf("%q drawn %d times, want about 4000", r, n)
+		}
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	tests := []struct {
+		charset string
+		length  int
+		want    float64
+	}{
+		{CharsetHex, 32, 128},
+		{CharsetURLSafe, 40, 240},
+		{CharsetAlnum, 10, 10 * math.Log2(62)},
+		{CharsetWords, 6, 6 * math.Log2(7776)},
+		{"base32", 10, 0},
+	}
+
+	for _, tt := range tests {
+		if got := Entropy(tt.charset, tt.length); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Entropy(%s, %d) = %.2f, want %.2f", tt.charset, tt.length, got, tt.want)
+		}
+	}
+}
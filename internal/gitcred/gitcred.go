@@ -0,0 +1,196 @@
+// Package gitcred implements the git credential helper protocol and maps the
+// hosts and paths git asks about to entries in the store.
+//
+// Git writes attributes as key=value lines, ended by a blank line or EOF, and
+// reads the answer of a "get" back in the same form.
+package gitcred
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Credential holds the attributes exchanged with git. Attributes deecli does
+// not use are dropped.
+type Credential struct {
+	Protocol string
+	Host     string
+	Path     string
+	Username string
+	Password string
+}
+
+// Read parses the attributes git writes to a helper.
+func Read(r io.Reader) (Credential, error) {
+	var c Credential
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return c, fmt.Errorf("invalid credential line %q", line)
+		}
+		switch key {
+		case "protocol":
+			c.Protocol = value
+		case "host":
+			c.Host = value
+		case "path":
+			c.Path = value
+		case "username":
+			c.Username = value
+		case "password":
+			c.Password = value
+		case "url":
+			if err := c.setURL(value); err != nil {
+				return c, err
+			}
+		}
+	}
+	return c, scanner.Err()
+}
+
+// setURL fills in the attributes of a url= line, as 'git credential' does.
+func (c *Credential) setURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid credential url: %w", err)
+	}
+	c.Protocol, c.Host, c.Path = u.Scheme, u.Host, strings.TrimPrefix(u.Path, "/")
+	if u.User != nil {
+		c.Username = u.User.Username()
+		if password, ok := u.User.Password(); ok {
+			c.Password = password
+		}
+	}
+	return nil
+}
+
+// Write sends the non-empty attributes of c back to git.
+func (c Credential) Write(w io.Writer) error {
+	for _, attr := range [][2]string{
+		{"protocol", c.Protocol},
+		{"host", c.Host},
+		{"path", c.Path},
+		{"username", c.Username},
+		{"password", c.Password},
+	} {
+		if attr[1] == "" {
+			continue
+		}
+		if strings.ContainsAny(attr[1], "\n\x00") {
+			return fmt.Errorf("credential %s contains a newline or NUL", attr[0])
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", attr[0], attr[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Mapping points the credentials of a host, and optionally a path below it,
+// at a stored token.
+type Mapping struct {
+	// Host is the host git asks about, e.g. github.com; it may be a glob
+	// such as *.example.com.
+	Host string `json:"host"`
+
+	// Path limits the mapping to a repository path or a prefix of one.
+	// Git only sends paths when credential.useHttpPath is set.
+	Path string `json:"path,omitempty"`
+
+	// Protocol limits the mapping to one protocol, e.g. https.
+	Protocol string `json:"protocol,omitempty"`
+
+	// Username is sent along with the token, and limits the mapping to
+	// requests for that user when git already knows one.
+	Username string `json:"username,omitempty"`
+
+	// Name is the token holding the password.
+	Name string `json:"name"`
+}
+
+// ParseTarget splits HOST[/PATH] as given on the command line.
+func ParseTarget(target string) (host, p string) {
+	host, p, _ = strings.Cut(strings.TrimPrefix(strings.TrimPrefix(target, "https://"), "http://"), "/")
+	return strings.ToLower(host), strings.Trim(p, "/")
+}
+
+// Matches reports whether m applies to c.
+func (m Mapping) Matches(c Credential) bool {
+	if ok, _ := path.Match(strings.ToLower(m.Host), strings.ToLower(c.Host)); !ok {
+		return false
+	}
+	if m.Protocol != "" && !strings.EqualFold(m.Protocol, c.Protocol) {
+		return false
+	}
+	if m.Username != "" && c.Username != "" && m.Username != c.Username {
+		return false
+	}
+	if m.Path == "" {
+		return true
+	}
+	p := strings.Trim(c.Path, "/")
+	if ok, _ := path.Match(m.Path, p); ok {
+		return true
+	}
+	return p == m.Path || strings.HasPrefix(p, m.Path+"/")
+}
+
+// SameTarget reports whether m and o apply to the same requests, so one
+// replaces the other.
+func (m Mapping) SameTarget(o Mapping) bool {
+	return strings.EqualFold(m.Host, o.Host) && m.Path == o.Path &&
+		strings.EqualFold(m.Protocol, o.Protocol) && m.Username == o.Username
+}
+
+// Match returns the most specific mapping for c: the longest path wins, then
+// an exact host over a glob, then a mapping pinned to a user or protocol, then
+// the earlier mapping.
+func Match(mappings []Mapping, c Credential) (Mapping, bool) {
+	best, found := Mapping{}, false
+	for _, m := range mappings {
+		if !m.Matches(c) {
+			continue
+		}
+		if !found || specificity(m) > specificity(best) {
+			best, found = m, true
+		}
+	}
+	return best, found
+}
+
+func specificity(m Mapping) int {
+	score := 4 * len(m.Path)
+	if !strings.ContainsAny(m.Host, "*?[") {
+		score += 3
+	}
+	if m.Username != "" {
+		score++
+	}
+	if m.Protocol != "" {
+		score++
+	}
+	return score
+}
+
+// DefaultName is the token name used for credentials git stores without a
+// mapping, e.g. git/octocat@github.com.
+func DefaultName(c Credential) string {
+	name := "git/"
+	if c.Username != "" {
+		name += c.Username + "@"
+	}
+	name += strings.ToLower(c.Host)
+	if p := strings.Trim(c.Path, "/"); p != "" {
+		name += "/" + p
+	}
+	return name
+}
@@ -0,0 +1,203 @@
+package gitcred
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    Credential
+		wantErr string
+	}{
+		{
+			name: "attributes",
+			in:   "protocol=https\nhost=github.com\npath=org/repo.git\nusername=octocat\n\n",
+			want: Credential{Protocol: "https", Host: "github.com", Path: "org/repo.git", Username: "octocat"},
+		},
+		{
+			name: "EOF without blank line",
+			in:   "protocol=https\nhost=github.com",
+			want: Credential{Protocol: "https", Host: "github.com"},
+		},
+		{
+			name: "CRLF line endings",
+			in:   "host=github.com\r\npassword=s3cret\r\n\r\n",
+			want: Credential{Host: "github.com", Password: "s3cret"},
+		},
+		{
+			name: "stops at the blank line",
+			in:   "host=github.com\n\nhost=evil.example\n",
+			want: Credential{Host: "github.com"},
+		},
+		{
+			name: "unknown attributes ignored",
+			in:   "host=github.com\ncapability[]=authtype\nwwwauth[]=Basic\n",
+			want: Credential{Host: "github.com"},
+		},
+		{
+			name: "value containing =",
+			in:   "host=github.com\npassword=a=b=c\n",
+			want: Credential{Host: "github.com", Password: "a=b=c"},
+		},
+		{
+			name: "url attribute",
+			in:   "url=https://octocat:pw@github.com:8443/org/repo.git\n",
+			want: Credential{Protocol: "https", Host: "github.com:8443", Path: "org/repo.git", Username: "octocat", Password: "pw"},
+		},
+		{
+			name:    "line without =",
+			in:      "host github.com\n",
+			wantErr: "invalid credential line",
+		},
+		{
+			name:    "invalid url",
+			in:      "url=http://[::1\n",
+			wantErr: "invalid credential url",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(strings.NewReader(tt.in))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Read returned %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Read = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       Credential
+		want    string
+		wantErr string
+	}{
+		{
+			name: "non-empty attributes in order",
+			c:    Credential{Host: "github.com", Username: "octocat", Password: "pw"},
+			want: "host=github.com\nusername=octocat\npassword=pw\n",
+		},
+		{
+			name:    "newline in password",
+			c:       Credential{Host: "github.com", Password: "pw\nhost=evil.example"},
+			wantErr: "password contains a newline",
+		},
+		{
+			name:    "NUL in username",
+			c:       Credential{Username: "octo\x00cat"},
+			wantErr: "username contains a newline or NUL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := tt.c.Write(&buf)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Write returned %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Write wrote %q, want %q", buf.String(), tt.want)
+			}
+
+			back, err := Read(&buf)
+			if err != nil || back != tt.c {
+				t.Errorf("Read(Write) = %+v, %v; want %+v", back, err, tt.c)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	mappings := []Mapping{
+		{Host: "github.com", Name: "github"},
+		{Host: "github.com", Path: "work", Name: "github-work"},
+		{Host: "github.com", Path: "work/secret-repo.git", Name: "github-secret"},
+		{Host: "bitbucket.org", Username: "bot", Name: "bitbucket-bot"},
+		{Host: "*.example.com", Name: "example-glob"},
+		{Host: "git.example.com", Name: "example"},
+		{Host: "gitlab.com", Protocol: "https", Name: "gitlab"},
+	}
+
+	tests := []struct {
+		name string
+		c    Credential
+		want string
+	}{
+		{"host only", Credential{Host: "github.com"}, "github"},
+		{"host is case-insensitive", Credential{Host: "GitHub.com"}, "github"},
+		{"path prefix", Credential{Host: "github.com", Path: "work/other.git"}, "github-work"},
+		{"longest path wins", Credential{Host: "github.com", Path: "/work/secret-repo.git"}, "github-secret"},
+		{"path is not a string prefix", Credential{Host: "github.com", Path: "workshop/repo.git"}, "github"},
+		{"pinned username", Credential{Host: "bitbucket.org", Username: "bot"}, "bitbucket-bot"},
+		{"pinned username when git knows none", Credential{Host: "bitbucket.org"}, "bitbucket-bot"},
+		{"other username", Credential{Host: "bitbucket.org", Username: "octocat"}, ""},
+		{"exact host beats glob", Credential{Host: "git.example.com"}, "example"},
+		{"glob", Credential{Host: "ci.example.com"}, "example-glob"},
+		{"protocol matches", Credential{Host: "gitlab.com", Protocol: "HTTPS"}, "gitlab"},
+		{"protocol mismatch", Credential{Host: "gitlab.com", Protocol: "http"}, ""},
+		{"unmapped host", Credential{Host: "codeberg.org"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, ok := Match(mappings, tt.c)
+			if ok != (tt.want != "") || m.Name != tt.want {
+				t.Errorf("Match = %q, %t; want %q", m.Name, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		in, host, path string
+	}{
+		{"github.com", "github.com", ""},
+		{"GitHub.com/Org/Repo/", "github.com", "Org/Repo"},
+		{"https://github.com/org", "github.com", "org"},
+		{"http://git.example.com", "git.example.com", ""},
+	}
+	for _, tt := range tests {
+		host, path := ParseTarget(tt.in)
+		if host != tt.host || path != tt.path {
+			t.Errorf("ParseTarget(%q) = %q, %q; want %q, %q", tt.in, host, path, tt.host, tt.path)
+		}
+	}
+}
+
+func TestDefaultName(t *testing.T) {
+	tests := []struct {
+		c    Credential
+		want string
+	}{
+		{Credential{Host: "GitHub.com"}, "git/github.com"},
+		{Credential{Host: "github.com", Username: "octocat"}, "git/octocat@github.com"},
+		{Credential{Host: "github.com", Username: "octocat", Path: "/org/repo.git"}, "git/octocat@github.com/org/repo.git"},
+	}
+	for _, tt := range tests {
+		if got := DefaultName(tt.c); got != tt.want {
+			t.Errorf("DefaultName(%+v) = %q, want %q", tt.c, got, tt.want)
+		}
+	}
+}
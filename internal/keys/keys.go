@@ -0,0 +1,191 @@
+// Package keys manages the X25519 keypairs used to share tokens between
+// teammates. Your identity lives in ~/.deecli/identity (private key, sealed
+// under a passphrase) and ~/.deecli/identity.pub; teammates' public keys are
+// kept in ~/.deecli/keys/<name>.pub.
+package keys
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/deeragoo/deecli/internal/envelope"
+	"github.com/deeragoo/deecli/internal/store"
+)
+
+// publicKeyPrefix starts every public key line.
+const publicKeyPrefix = "deecli-x25519"
+
+// ErrNoIdentity is returned when no keypair has been generated yet.
+var ErrNoIdentity = errors.New("no keypair found (run 'deecli keys generate')")
+
+// namePattern matches the names under which teammates' keys are stored.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@-]*$`)
+
+// PublicKey is an X25519 public key with a free-form label such as an email.
+type PublicKey struct {
+	Key   [32]byte
+	Label string
+}
+
+// String renders the key in the one-line format of .pub files.
+func (k PublicKey) String() string {
+	s := publicKeyPrefix + " " + base64.StdEncoding.EncodeToString(k.Key[:])
+	if k.Label != "" {
+		s += " " + k.Label
+	}
+	return s
+}
+
+// ParsePublicKey parses a line written by PublicKey.String.
+func ParsePublicKey(text string) (PublicKey, error) {
+	fields := strings.Fields(strings.TrimSpace(text))
+	if len(fields) < 2 || fields[0] != publicKeyPrefix {
+		return PublicKey{}, errors.New("not a deecli public key")
+	}
+	raw, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil || len(raw) != 32 {
+		return PublicKey{}, errors.New("malformed public key")
+	}
+
+	var k PublicKey
+	copy(k.Key[:], raw)
+	k.Label = strings.Join(fields[2:], " ")
+	return k, nil
+}
+
+// Dir returns the directory holding the keypair and teammates' keys.
+func Dir() string {
+	return filepath.Join(os.Getenv("HOME"), ".deecli")
+}
+
+// IdentityPath returns the location of the sealed private key.
+func IdentityPath() string {
+	return filepath.Join(Dir(), "identity")
+}
+
+// PublicPath returns the location of your own public key.
+func PublicPath() string {
+	return IdentityPath() + ".pub"
+}
+
+// Generate creates a keypair, seals the private key under passphrase and
+// writes both halves. It refuses to replace an existing keypair.
+func Generate(passphrase, label string) (PublicKey, error) {
+	if _, err := os.Stat(IdentityPath()); err == nil {
+		return PublicKey{}, fmt.Errorf("keypair already exists at %s", IdentityPath())
+	}
+
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return PublicKey{}, err
+	}
+
+	sealed, err := envelope.Seal(priv[:], passphrase, envelope.DefaultParams)
+	if err != nil {
+		return PublicKey{}, fmt.Errorf("error sealing private key: %w", err)
+	}
+
+	if err := os.MkdirAll(Dir(), 0o700); err != nil {
+		return PublicKey{}, err
+	}
+	if err := store.WriteFile(IdentityPath(), []byte(sealed+"\n")); err != nil {
+		return PublicKey{}, fmt.Errorf("error writing private key: %w", err)
+	}
+
+	public := PublicKey{Key: *pub, Label: label}
+	if err := store.WriteFile(PublicPath(), []byte(public.String()+"\n")); err != nil {
+		return PublicKey{}, fmt.Errorf("error writing public key: %w", err)
+	}
+	return public, nil
+}
+
+// LoadPublic returns your own public key.
+func LoadPublic() (PublicKey, error) {
+	data, err := os.ReadFile(PublicPath())
+	if os.IsNotExist(err) {
+		return PublicKey{}, ErrNoIdentity
+	} else if err != nil {
+		return PublicKey{}, err
+	}
+	return ParsePublicKey(string(data))
+}
+
+// LoadPrivate unseals your private key with passphrase.
+func LoadPrivate(passphrase string) (*[32]byte, error) {
+	data, err := os.ReadFile(IdentityPath())
+	if os.IsNotExist(err) {
+		return nil, ErrNoIdentity
+	} else if err != nil {
+		return nil, err
+	}
+
+	raw, err := envelope.Open(strings.TrimSpace(string(data)), passphrase)
+	if err != nil {
+		return nil, errors.New("incorrect private key passphrase")
+	}
+	if len(raw) != 32 {
+		return nil, errors.New("private key has unexpected length")
+	}
+
+	var priv [32]byte
+	copy(priv[:], raw)
+	return &priv, nil
+}
+
+// recipientPath returns where the public key of teammate name is stored.
+func recipientPath(name string) string {
+	return filepath.Join(Dir(), "keys", name+".pub")
+}
+
+// ImportRecipient stores a teammate's public key under name.
+func ImportRecipient(name string, key PublicKey, force bool) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid key name %q", name)
+	}
+	path := recipientPath(name)
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("a key named %q already exists; use --force to replace it", name)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return store.WriteFile(path, []byte(key.String()+"\n"))
+}
+
+// Recipient returns the public key stored under name.
+func Recipient(name string) (PublicKey, error) {
+	if !namePattern.MatchString(name) {
+		return PublicKey{}, fmt.Errorf("invalid key name %q", name)
+	}
+	data, err := os.ReadFile(recipientPath(name))
+	if os.IsNotExist(err) {
+		return PublicKey{}, fmt.Errorf("no public key for %q (import it with 'deecli keys import')", name)
+	} else if err != nil {
+		return PublicKey{}, err
+	}
+	return ParsePublicKey(string(data))
+}
+
+// Recipients returns the names of all imported public keys, sorted.
+func Recipients() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(Dir(), "keys", "*.pub"))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(matches))
+	for _, match := range matches {
+		names = append(names, strings.TrimSuffix(filepath.Base(match), ".pub"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,111 @@
+package keys
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParsePublicKey(t *testing.T) {
+	pub, _ := newKeypair(t)
+	got, err := ParsePublicKey(pub.String() + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != pub {
+		t.Errorf("parsed %+v, want %+v", got, pub)
+	}
+
+	unlabelled := PublicKey{Key: pub.Key}
+	if got, err := ParsePublicKey(unlabelled.String()); err != nil || got != unlabelled {
+		t.Errorf("ParsePublicKey(%q) = %+v, %v", unlabelled.String(), got, err)
+	}
+
+	for _, text := range []string{
+		"",
+		"ssh-ed25519 AAAA alice",
+		publicKeyPrefix + " not-base64!",
+		publicKeyPrefix + " AAAA",
+	} {
+		if _, err := ParsePublicKey(text); err == nil {
+			t.Errorf("ParsePublicKey(%q) succeeded", text)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := LoadPublic(); !errors.Is(err, ErrNoIdentity) {
+		t.Fatalf("LoadPublic before Generate returned %v, want ErrNoIdentity", err)
+	}
+	pub, err := Generate("tangerine orbit velvet canyon", "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Generate("again", ""); err == nil {
+		t.Error("second Generate replaced the keypair")
+	}
+
+	info, err := os.Stat(IdentityPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("private key has mode %v, want 0600", info.Mode().Perm())
+	}
+	if loaded, err := LoadPublic(); err != nil || loaded != pub {
+		t.Errorf("LoadPublic = %+v, %v; want %+v", loaded, err, pub)
+	}
+
+	priv, err := LoadPrivate("tangerine orbit velvet canyon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := SealShare(Share{Name: "github", Value: "token"}, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenShare(sealed, pub, priv); err != nil {
+		t.Errorf("the loaded private key does not match the public key: %v", err)
+	}
+	if _, err := LoadPrivate("wrong"); err == nil {
+		t.Error("LoadPrivate accepted a wrong passphrase")
+	}
+}
+
+func TestRecipients(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	bob, _ := newKeypair(t)
+	carol, _ := newKeypair(t)
+
+	if err := ImportRecipient("carol", carol, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := ImportRecipient("bob", bob, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := ImportRecipient("bob", carol, false); err == nil {
+		t.Error("ImportRecipient replaced a key without force")
+	}
+	for _, name := range []string{"../bob", ".hidden", "a b", ""} {
+		if err := ImportRecipient(name, bob, true); err == nil {
+			t.Errorf("ImportRecipient accepted the name %q", name)
+		}
+	}
+
+	names, err := Recipients()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"bob", "carol"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Recipients = %v, want %v", names, want)
+	}
+	if got, err := Recipient("bob"); err != nil || got != bob {
+		t.Errorf("Recipient(bob) = %+v, %v", got, err)
+	}
+	if _, err := Recipient("dave"); err == nil {
+		t.Error("Recipient returned a key that was never imported")
+	}
+}
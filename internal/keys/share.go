@@ -0,0 +1,72 @@
+package keys
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+// sharePrefix starts every sealed share so it can be told apart from a path.
+const sharePrefix = "deecli-share:v1:"
+
+// Share is the plaintext of a sealed token handed to a teammate.
+type Share struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+
+	// From is the sender's public key label, for display only; it is not
+	// authenticated.
+	From string `json:"from,omitempty"`
+
+	Description string `json:"description,omitempty"`
+}
+
+// IsSealedShare reports whether text looks like the output of SealShare.
+func IsSealedShare(text string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), sharePrefix)
+}
+
+// SealShare encrypts s so only the holder of to's private key can open it.
+// It uses an anonymous NaCl box: an ephemeral X25519 key, XSalsa20-Poly1305.
+func SealShare(s Share, to PublicKey) (string, error) {
+	plaintext, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("JSON marshal error: %w", err)
+	}
+	sealed, err := box.SealAnonymous(nil, plaintext, &to.Key, rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	return sharePrefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// OpenShare decrypts a share sealed to pub with the matching private key.
+func OpenShare(text string, pub PublicKey, priv *[32]byte) (Share, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(text), sharePrefix)
+	if !ok {
+		return Share{}, errors.New("not a deecli share")
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Share{}, errors.New("malformed share")
+	}
+
+	plaintext, ok := box.OpenAnonymous(nil, sealed, &pub.Key, priv)
+	if !ok {
+		return Share{}, errors.New("cannot open share: it was sealed for a different key or is corrupted")
+	}
+
+	var s Share
+	if err := json.Unmarshal(plaintext, &s); err != nil {
+		return Share{}, fmt.Errorf("error decoding share: %w", err)
+	}
+	if s.Name == "" || s.Value == "" {
+		return Share{}, errors.New("share has no token")
+	}
+	return s, nil
+}
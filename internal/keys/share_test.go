@@ -0,0 +1,83 @@
+package keys
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+func newKeypair(t *testing.T) (PublicKey, *[32]byte) {
+	t.Helper()
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return PublicKey{Key: *pub, Label: "alice@example.com"}, priv
+}
+
+func TestShareRoundTrip(t *testing.T) {
+	pub, priv := newKeypair(t)
+	want := Share{Name: "github", Value: "ghp_0123456789", From: "bob@example.com", Description: "CI token"}
+
+	sealed, err := SealShare(want, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealedShare(sealed) || !IsSealedShare("  "+sealed+"\n") {
+		t.Errorf("IsSealedShare(%q) = false", sealed)
+	}
+	if strings.Contains(sealed, want.Value) {
+		t.Error("sealed share contains the token in plaintext")
+	}
+
+	got, err := OpenShare(sealed+"\n", pub, priv)
+	if err != nil {
+		t.Fatalf("OpenShare: %v", err)
+	}
+	if got != want {
+		t.Errorf("opened %+v, want %+v", got, want)
+	}
+}
+
+func TestOpenShareRejects(t *testing.T) {
+	pub, priv := newKeypair(t)
+	otherPub, otherPriv := newKeypair(t)
+
+	sealed, err := SealShare(Share{Name: "github", Value: "token"}, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(sealed, sharePrefix))
+	raw[len(raw)-1] ^= 1
+	tampered := sharePrefix + base64.RawURLEncoding.EncodeToString(raw)
+	empty, err := SealShare(Share{Name: "github"}, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		pub     PublicKey
+		priv    *[32]byte
+		wantErr string
+	}{
+		{"not a share", "./github.share", pub, priv, "not a deecli share"},
+		{"malformed", sharePrefix + "!!!", pub, priv, "malformed share"},
+		{"other recipient", sealed, otherPub, otherPriv, "sealed for a different key"},
+		{"tampered", tampered, pub, priv, "sealed for a different key or is corrupted"},
+		{"no value", empty, pub, priv, "share has no token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := OpenShare(tt.text, tt.pub, tt.priv)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("OpenShare returned %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package otp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 4226 appendix D.
+func TestHOTP(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	want := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+	for counter, code := range want {
+		got, err := HOTP(secret, uint64(counter), 6, SHA1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != code {
+			t.Errorf("HOTP(counter %d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+// RFC 6238 appendix B, with the seed of each hash padded to its output size.
+func TestTOTP(t *testing.T) {
+	seeds := map[string][]byte{
+		SHA1:   []byte("12345678901234567890"),
+		SHA256: []byte("12345678901234567890123456789012"),
+		SHA512: []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+	tests := []struct {
+		unix      int64
+		algorithm string
+		want      string
+	}{
+		{59, SHA1, "94287082"},
+		{59, SHA256, "46119246"},
+		{59, SHA512, "90693936"},
+		{1111111109, SHA1, "07081804"},
+		{1111111109, SHA256, "68084774"},
+		{1111111109, SHA512, "25091201"},
+		{1111111111, SHA1, "14050471"},
+		{1111111111, SHA256, "67062674"},
+		{1111111111, SHA512, "99943326"},
+		{1234567890, SHA1, "89005924"},
+		{1234567890, SHA256, "91819424"},
+		{1234567890, SHA512, "93441116"},
+		{2000000000, SHA1, "69279037"},
+		{2000000000, SHA256, "90698825"},
+		{2000000000, SHA512, "38618901"},
+		{20000000000, SHA1, "65353130"},
+		{20000000000, SHA256, "77737706"},
+		{20000000000, SHA512, "47863826"},
+	}
+
+	for _, tt := range tests {
+		k := &Key{Secret: seeds[tt.algorithm], Algorithm: tt.algorithm, Digits: 8, Period: 30}
+		got, _, err := k.Code(time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s at %d = %s, want %s", tt.algorithm, tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeRemaining(t *testing.T) {
+	k := &Key{Secret: []byte("12345678901234567890"), Algorithm: SHA1, Digits: 6, Period: 30}
+	for unix, want := range map[int64]time.Duration{0: 30 * time.Second, 59: time.Second, 61: 29 * time.Second} {
+		if _, remaining, _ := k.Code(time.Unix(unix, 0)); remaining != want {
+			t.Errorf("remaining at %d = %s, want %s", unix, remaining, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    Key
+		wantErr string
+	}{
+		{
+			name: "bare seed",
+			in:   "gezd gnbv-gy3t qojq",
+			want: Key{Secret: []byte("1234567890"), Algorithm: SHA1, Digits: 6, Period: 30},
+		},
+		{
+			name: "URI with issuer in label",
+			in:   "otpauth://totp/GitHub:octocat?secret=GEZDGNBVGY3TQOJQ&digits=8&period=60&algorithm=sha256",
+			want: Key{Secret: []byte("1234567890"), Algorithm: SHA256, Digits: 8, Period: 60, Issuer: "GitHub", Account: "octocat"},
+		},
+		{
+			name: "issuer parameter wins",
+			in:   "otpauth://totp/Old:octocat?secret=GEZDGNBVGY3TQOJQ&issuer=New",
+			want: Key{Secret: []byte("1234567890"), Algorithm: SHA1, Digits: 6, Period: 30, Issuer: "New", Account: "octocat"},
+		},
+		{name: "hotp URI", in: "otpauth://hotp/x?secret=GEZDGNBVGY3TQOJQ", wantErr: "only totp"},
+		{name: "invalid seed", in: "not base32!", wantErr: "not valid base32"},
+		{name: "too few digits", in: "otpauth://totp/x?secret=GEZDGNBVGY3TQOJQ&digits=4", wantErr: "between 6 and 10"},
+		{name: "unknown algorithm", in: "otpauth://totp/x?secret=GEZDGNBVGY3TQOJQ&algorithm=MD5", wantErr: "unsupported algorithm"},
+		{name: "zero period", in: "otpauth://totp/x?secret=GEZDGNBVGY3TQOJQ&period=0", wantErr: "period must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := Parse(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse returned %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if string(k.Secret) != string(tt.want.Secret) || k.Algorithm != tt.want.Algorithm || k.Digits != tt.want.Digits ||
+				k.Period != tt.want.Period || k.Issuer != tt.want.Issuer || k.Account != tt.want.Account {
+				t.Errorf("Parse = %+v, want %+v", *k, tt.want)
+			}
+
+			again, err := Parse(k.URI())
+			if err != nil {
+				t.Fatalf("Parse(URI()): %v", err)
+			}
+			if again.URI() != k.URI() {
+				t.Errorf("URI does not round-trip: %s became %s", k.URI(), again.URI())
+			}
+		})
+	}
+}
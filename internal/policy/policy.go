@@ -0,0 +1,115 @@
+// Package policy enforces the passphrase strength policy from the store
+// settings and throttles repeated wrong passphrases.
+package policy
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/deeragoo/deecli/internal/settings"
+)
+
+//go:embed common.txt
+var commonList string
+
+// common holds the blocklisted passwords, lower-cased.
+var common = func() map[string]bool {
+	m := map[string]bool{}
+	for _, line := range strings.Split(commonList, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			m[line] = true
+		}
+	}
+	return m
+}()
+
+// Enforce checks a newly chosen passphrase against the saved policy. Every
+// new passphrase, whether for the vault, a token, a bundle or a private key,
+// goes through Enforce.
+func Enforce(passphrase string) error {
+	s, err := settings.Load()
+	if err != nil {
+		return err
+	}
+	return check(passphrase, s.PassphrasePolicy())
+}
+
+// check reports why passphrase does not satisfy p, or nil.
+func check(passphrase string, p settings.Policy) error {
+	if passphrase == "" {
+		return errors.New("passphrase must not be empty")
+	}
+	if n := utf8.RuneCountInString(passphrase); n < p.MinLength {
+		return fmt.Errorf("passphrase is too short (%d characters, policy requires %d); several random words are easy to remember", n, p.MinLength)
+	}
+	if p.Blocklist && Common(passphrase) {
+		return errors.New("passphrase is too common; choose something less guessable")
+	}
+	if bits := Estimate(passphrase); p.MinEntropy > 0 && bits < float64(p.MinEntropy) {
+		return fmt.Errorf("passphrase is too weak (about %.0f bits, policy requires %d); use a longer passphrase or several random words", bits, p.MinEntropy)
+	}
+	return nil
+}
+
+// Common reports whether passphrase is on the blocklist, ignoring case and
+// trailing digits or punctuation ("Password123!" counts as "password").
+func Common(passphrase string) bool {
+	lower := strings.ToLower(passphrase)
+	if common[lower] {
+		return true
+	}
+	stem := strings.TrimRightFunc(lower, func(r rune) bool {
+		return unicode.IsDigit(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+	return stem != "" && common[stem]
+}
+
+// Estimate gives a rough strength in bits: the size of the character classes
+// used, counted once per character that does not simply repeat or continue a
+// run from the previous one ("aaaa" and "abcd" count as one character).
+func Estimate(passphrase string) float64 {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range passphrase {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < 128:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	novel := 0
+	var prev rune = -1
+	for _, r := range passphrase {
+		if d := r - prev; prev < 0 || (d != 0 && d != 1 && d != -1) {
+			novel++
+		}
+		prev = r
+	}
+	return float64(novel) * math.Log2(float64(pool))
+}
@@ -0,0 +1,104 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deeragoo/deecli/internal/settings"
+)
+
+func TestCommon(t *testing.T) {
+	tests := []struct {
+		passphrase string
+		want       bool
+	}{
+		{"password", true},
+		{"Password123!", true},
+		{"P@ssw0rd", true},
+		{"qwertyuiop", true},
+		{"1q2w3e4r5t6y", true},
+		{"1234567890", true},
+		{"Dragon2024?", true},
+		{"correcthorsebatterystaple", true},
+		{"123", false},
+		{"!!!", false},
+		{"tangerine orbit velvet canyon", false},
+		{"password manager", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.passphrase, func(t *testing.T) {
+			if got := Common(tt.passphrase); got != tt.want {
+				t.Errorf("Common(%q) = %t, want %t", tt.passphrase, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommonListSize(t *testing.T) {
+	if len(common) < 1000 {
+		t.Errorf("blocklist has %d entries, want at least 1000", len(common))
+	}
+	for word := range common {
+		if word != strings.ToLower(word) {
+			t.Errorf("blocklist entry %q is not lower-case and can never match", word)
+		}
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		passphrase string
+		min, max   float64
+	}{
+		{"", 0, 0},
+		{"aaaaaaaaaaaa", 4.7, 4.71},
+		{"abcdefghijkl", 4.7, 4.71},
+		{"zyxwvutsrqpo", 4.7, 4.71},
+		{"ab12", 10, 15},
+		{"tangerine orbit velvet canyon", 100, 200},
+		{"Tr0ub4dor&3x", 70, 80},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.passphrase, func(t *testing.T) {
+			if got := Estimate(tt.passphrase); got < tt.min || got > tt.max {
+				t.Errorf("Estimate(%q) = %.1f, want between %.1f and %.1f", tt.passphrase, got, tt.min, tt.max)
+			}
+		})
+	}
+
+	if Estimate("kx9#Qm2!vL7p") <= Estimate("kx9qm2vl7pab") {
+		t.Error("mixing character classes did not raise the estimate")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+		policy     settings.Policy
+		wantErr    string
+	}{
+		{"empty under no policy", "", settings.Policy{}, "must not be empty"},
+		{"anything else under no policy", "x", settings.Policy{}, ""},
+		{"default rejects short", "kx9#Qm2!", settings.DefaultPolicy, "too short"},
+		{"default rejects common", "Password1234!", settings.DefaultPolicy, "too common"},
+		{"default accepts words", "tangerine orbit velvet canyon", settings.DefaultPolicy, ""},
+		{"length counts characters, not bytes", "ééééé", settings.Policy{MinLength: 6}, "too short"},
+		{"entropy", "aaaaaaaaaaaaaaaa", settings.Policy{MinEntropy: 40}, "too weak"},
+		{"relaxed", "monkey", settings.Policy{MinLength: 4}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := check(tt.passphrase, tt.policy)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("check(%q) = %v, want nil", tt.passphrase, err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("check(%q) = %v, want an error containing %q", tt.passphrase, err, tt.wantErr)
+			}
+		})
+	}
+}
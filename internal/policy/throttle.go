@@ -0,0 +1,128 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/deeragoo/deecli/internal/settings"
+	"github.com/deeragoo/deecli/internal/store"
+)
+
+// FreeAttempts is how many consecutive wrong passphrases are allowed before
+// decryption is delayed.
+const FreeAttempts = 3
+
+// MaxDelay caps the delay between attempts.
+const MaxDelay = 15 * time.Minute
+
+// attempts is the counter persisted beside the store.
+type attempts struct {
+	Failures int       `json:"failures"`
+	Last     time.Time `json:"last"`
+}
+
+// AttemptsPath returns the location of the wrong-passphrase counter.
+func AttemptsPath() string {
+	return store.SidecarPath("attempts")
+}
+
+// Delay returns the wait imposed after failures consecutive wrong
+// passphrases: nothing for the first FreeAttempts, then 1s, 2s, 4s, ...
+// up to MaxDelay.
+func Delay(failures int) time.Duration {
+	if failures < FreeAttempts {
+		return 0
+	}
+	n := failures - FreeAttempts
+	if n >= 20 {
+		return MaxDelay
+	}
+	return min(time.Second<<n, MaxDelay)
+}
+
+// Wait blocks until another decryption attempt is allowed, when throttling
+// is enabled.
+func Wait() error {
+	if !throttled() {
+		return nil
+	}
+	a, err := loadAttempts()
+	if err != nil {
+		return err
+	}
+
+	remaining := time.Until(a.Last.Add(Delay(a.Failures)))
+	if remaining <= 0 {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "%d wrong passphrase(s) in a row; waiting %s before trying again...\n", a.Failures, remaining.Round(time.Second))
+	time.Sleep(remaining)
+	return nil
+}
+
+// Failed records a wrong passphrase.
+func Failed() error {
+	if !throttled() {
+		return nil
+	}
+	return updateAttempts(func(a *attempts) {
+		a.Failures++
+		a.Last = time.Now().UTC()
+	})
+}
+
+// Succeeded clears the counter after a correct passphrase.
+func Succeeded() error {
+	if !throttled() {
+		return nil
+	}
+	if _, err := os.Stat(AttemptsPath()); os.IsNotExist(err) {
+		return nil
+	}
+	return updateAttempts(func(a *attempts) { *a = attempts{} })
+}
+
+func throttled() bool {
+	s, err := settings.Load()
+	return err == nil && s.PassphrasePolicy().Throttle
+}
+
+func loadAttempts() (attempts, error) {
+	var a attempts
+	data, err := os.ReadFile(AttemptsPath())
+	if os.IsNotExist(err) {
+		return a, nil
+	} else if err != nil {
+		return a, fmt.Errorf("error reading attempt counter: %w", err)
+	}
+	if err := json.Unmarshal(data, &a); err != nil {
+		return a, fmt.Errorf("error decoding attempt counter: %w", err)
+	}
+	return a, nil
+}
+
+func updateAttempts(update func(*attempts)) error {
+	unlock, err := store.Lock(AttemptsPath())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if uerr := unlock(); uerr != nil {
+			fmt.Fprintln(os.Stderr, "Warning: failed to release lock:", uerr)
+		}
+	}()
+
+	a, err := loadAttempts()
+	if err != nil {
+		return err
+	}
+	update(&a)
+
+	data, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("JSON marshal error: %w", err)
+	}
+	return store.WriteFile(AttemptsPath(), data)
+}
@@ -0,0 +1,29 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{FreeAttempts - 1, 0},
+		{FreeAttempts, time.Second},
+		{FreeAttempts + 1, 2 * time.Second},
+		{FreeAttempts + 4, 16 * time.Second},
+		{FreeAttempts + 9, 512 * time.Second},
+		{FreeAttempts + 10, MaxDelay},
+		{FreeAttempts + 64, MaxDelay},
+		{1 << 30, MaxDelay},
+	}
+
+	for _, tt := range tests {
+		if got := Delay(tt.failures); got != tt.want {
+			t.Errorf("Delay(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Package recovery turns a vault key or a token into printable Shamir shares
+// and back.
+//
+// A share is one line: deecli-shard:v1:<set>:<threshold>:<index>:<data>,
+// where set is a random identifier common to all shares of one split. The
+// shared payload carries its own checksum, so a wrong combination of shares
+// is detected instead of yielding a corrupt key.
+package recovery
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/deeragoo/deecli/internal/shamir"
+)
+
+const sharePrefix = "deecli-shard:v1:"
+
+const checksumSize = 8
+
+// Kinds of secret that can be split.
+const (
+	KindVaultKey = "vault-key"
+	KindToken    = "token"
+)
+
+// Secret is what the shares reconstruct.
+type Secret struct {
+	Kind string `json:"kind"`
+
+	// Name is the token name for KindToken.
+	Name string `json:"name,omitempty"`
+
+	Value []byte `json:"value"`
+}
+
+// Split encodes secret as n printable shares, any threshold of which
+// reconstruct it.
+func Split(secret Secret, n, threshold int) ([]string, error) {
+	payload, err := json.Marshal(secret)
+	if err != nil {
+		return nil, fmt.Errorf("JSON marshal error: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	payload = append(payload, sum[:checksumSize]...)
+
+	shares, err := shamir.Split(payload, n, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	set := make([]byte, 4)
+	if _, err := rand.Read(set); err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, len(shares))
+	for i, s := range shares {
+		lines[i] = fmt.Sprintf("%s%s:%d:%d:%s", sharePrefix, hex.EncodeToString(set), threshold, s.X,
+			base64.RawURLEncoding.EncodeToString(s.Y))
+	}
+	return lines, nil
+}
+
+// IsShare reports whether line looks like a share written by Split.
+func IsShare(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), sharePrefix)
+}
+
+// Combine reconstructs the secret from share lines. All shares must come from
+// the same split, and at least its threshold of them are needed.
+func Combine(lines []string) (Secret, error) {
+	var shares []shamir.Share
+	set, threshold := "", 0
+	for _, line := range lines {
+		fields := strings.Split(strings.TrimPrefix(strings.TrimSpace(line), sharePrefix), ":")
+		if !IsShare(line) || len(fields) != 4 {
+			return Secret{}, errors.New("malformed share")
+		}
+
+		k, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return Secret{}, errors.New("malformed share threshold")
+		}
+		x, err := strconv.ParseUint(fields[2], 10, 8)
+		if err != nil || x == 0 {
+			return Secret{}, errors.New("malformed share index")
+		}
+		y, err := base64.RawURLEncoding.DecodeString(fields[3])
+		if err != nil {
+			return Secret{}, errors.New("malformed share data")
+		}
+
+		if set == "" {
+			set, threshold = fields[0], k
+		} else if fields[0] != set || k != threshold {
+			return Secret{}, errors.New("shares come from different splits")
+		}
+		shares = append(shares, shamir.Share{X: byte(x), Y: y})
+	}
+
+	if len(shares) < threshold {
+		return Secret{}, fmt.Errorf("need %d shares, got %d", threshold, len(shares))
+	}
+
+	payload, err := shamir.Combine(shares)
+	if err != nil {
+		return Secret{}, err
+	}
+	if len(payload) <= checksumSize {
+		return Secret{}, errors.New("recovered data is too short")
+	}
+	data, checksum := payload[:len(payload)-checksumSize], payload[len(payload)-checksumSize:]
+	if sum := sha256.Sum256(data); !bytes.Equal(sum[:checksumSize], checksum) {
+		return Secret{}, errors.New("recovered data failed its checksum; a share is corrupted")
+	}
+
+	var secret Secret
+	if err := json.Unmarshal(data, &secret); err != nil {
+		return Secret{}, fmt.Errorf("error decoding recovered data: %w", err)
+	}
+	return secret, nil
+}
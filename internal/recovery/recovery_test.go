@@ -0,0 +1,63 @@
+package recovery
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSplitCombine(t *testing.T) {
+	secret := Secret{Kind: KindToken, Name: "github", Value: []byte("ghp_0123456789")}
+	lines, err := Split(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Split(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupted := append([]string(nil), lines...)
+	corrupted[1] = corrupted[1][:len(corrupted[1])-2] + flip(corrupted[1][len(corrupted[1])-2:])
+
+	tests := []struct {
+		name    string
+		lines   []string
+		wantErr string
+	}{
+		{"threshold", lines[:3], ""},
+		{"all shares", lines, ""},
+		{"surrounding whitespace", []string{"  " + lines[4] + "\n", lines[2], lines[0]}, ""},
+		{"below threshold", lines[:2], "need 3 shares, got 2"},
+		{"corrupted share", corrupted[:3], "failed its checksum"},
+		{"mixed splits", []string{lines[0], lines[1], other[2]}, "different splits"},
+		{"malformed share", []string{lines[0], "deecli-shard:v1:zz", lines[2]}, "malformed share"},
+		{"not a share", []string{lines[0], "hello", lines[2]}, "malformed share"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Combine(tt.lines)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Combine returned %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Combine: %v", err)
+			}
+			if got.Kind != secret.Kind || got.Name != secret.Name || !bytes.Equal(got.Value, secret.Value) {
+				t.Errorf("recovered %+v, want %+v", got, secret)
+			}
+		})
+	}
+}
+
+// flip changes the first character of a base64url suffix to another valid
+// one.
+func flip(s string) string {
+	if s[0] == 'A' {
+		return "B" + s[1:]
+	}
+	return "A" + s[1:]
+}
@@ -0,0 +1,135 @@
+// Package settings stores store-level options in ~/.secrets.settings.json,
+// next to the secrets file they apply to.
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/deeragoo/deecli/internal/gitcred"
+	"github.com/deeragoo/deecli/internal/store"
+)
+
+// Factors values: what must be presented to unlock the store.
+const (
+	FactorPassphrase        = "passphrase"
+	FactorKeyfile           = "keyfile"
+	FactorPassphraseKeyfile = "passphrase+keyfile"
+)
+
+// Settings are the store-level options. The zero value means the defaults.
+type Settings struct {
+	// Factors is one of the Factor constants; empty means FactorPassphrase.
+	Factors string `json:"factors,omitempty"`
+
+	// Keyfile is the keyfile path used when Factors includes a keyfile.
+	Keyfile string `json:"keyfile,omitempty"`
+
+	// Policy overrides DefaultPolicy when set.
+	Policy *Policy `json:"policy,omitempty"`
+
+	// History overrides DefaultHistory when set.
+	History *History `json:"history,omitempty"`
+
+	// GitCredentials maps the hosts git asks 'deecli git-credential' about
+	// to stored tokens.
+	GitCredentials []gitcred.Mapping `json:"git_credentials,omitempty"`
+}
+
+// Policy governs new passphrases and wrong-passphrase attempts.
+type Policy struct {
+	// MinLength is the minimum number of characters in a new passphrase;
+	// 0 disables the check.
+	MinLength int `json:"min_length"`
+
+	// MinEntropy is the minimum estimated strength of a new passphrase, in
+	// bits; 0 disables the check.
+	MinEntropy int `json:"min_entropy"`
+
+	// Blocklist rejects new passphrases found in the list of common passwords.
+	Blocklist bool `json:"blocklist"`
+
+	// Throttle delays decryption after repeated wrong passphrases.
+	Throttle bool `json:"throttle"`
+}
+
+// DefaultPolicy applies when no policy is saved: new passphrases need 12
+// characters and must not be common passwords. 'deecli policy set' can relax
+// or tighten it.
+var DefaultPolicy = Policy{MinLength: 12, Blocklist: true}
+
+// History governs how many earlier values of each token are kept.
+type History struct {
+	// Keep is the number of earlier values kept per token; 0 keeps none.
+	Keep int `json:"keep"`
+
+	// MaxAgeDays drops earlier values replaced more than this many days ago;
+	// 0 keeps them regardless of age.
+	MaxAgeDays int `json:"max_age_days"`
+}
+
+// DefaultHistory applies when no history policy is saved.
+var DefaultHistory = History{Keep: store.DefaultHistoryKeep}
+
+// Path returns the location of the settings file.
+func Path() string {
+	return store.SidecarPath("settings")
+}
+
+// Load reads the settings, returning the defaults when none are saved.
+func Load() (Settings, error) {
+	var s Settings
+	data, err := os.ReadFile(Path())
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return s, fmt.Errorf("error reading settings: %w", err)
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("error decoding settings: %w", err)
+	}
+	return s, nil
+}
+
+// Save atomically writes the settings with owner-only permissions.
+func (s Settings) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON marshal error: %w", err)
+	}
+	if err := store.WriteFile(Path(), append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing settings: %w", err)
+	}
+	return nil
+}
+
+// PassphrasePolicy returns Policy, or DefaultPolicy when none is saved.
+func (s Settings) PassphrasePolicy() Policy {
+	if s.Policy == nil {
+		return DefaultPolicy
+	}
+	return *s.Policy
+}
+
+// HistoryPolicy returns History, or DefaultHistory when none is saved.
+func (s Settings) HistoryPolicy() History {
+	if s.History == nil {
+		return DefaultHistory
+	}
+	return *s.History
+}
+
+// Retention converts h to the store's retention rules.
+func (h History) Retention() store.Retention {
+	return store.Retention{Keep: h.Keep, MaxAge: time.Duration(h.MaxAgeDays) * 24 * time.Hour}
+}
+
+// FactorMode returns Factors with the default applied.
+func (s Settings) FactorMode() string {
+	if s.Factors == "" {
+		return FactorPassphrase
+	}
+	return s.Factors
+}
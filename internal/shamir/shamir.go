@@ -0,0 +1,127 @@
+// Package shamir implements Shamir's secret sharing over GF(2^8): a secret is
+// split into n shares such that any k of them reconstruct it and fewer reveal
+// nothing about it.
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaxShares is the largest number of shares a secret can be split into.
+const MaxShares = 255
+
+// Share is one point of every byte's polynomial: X is the evaluation point
+// (never zero) and Y holds one value per secret byte.
+type Share struct {
+	X byte
+	Y []byte
+}
+
+// Split divides secret into n shares, any threshold of which recover it.
+func Split(secret []byte, n, threshold int) ([]Share, error) {
+	switch {
+	case len(secret) == 0:
+		return nil, errors.New("secret must not be empty")
+	case threshold < 2:
+		return nil, errors.New("threshold must be at least 2")
+	case n < threshold:
+		return nil, errors.New("number of shares must be at least the threshold")
+	case n > MaxShares:
+		return nil, fmt.Errorf("at most %d shares are supported", MaxShares)
+	}
+
+	shares := make([]Share, n)
+	for i := range shares {
+		shares[i] = Share{X: byte(i + 1), Y: make([]byte, len(secret))}
+	}
+
+	// One random polynomial of degree threshold-1 per byte, with the secret
+	// byte as the constant term
+	coeffs := make([]byte, threshold)
+	for b, s := range secret {
+		coeffs[0] = s
+		if _, err := io.ReadFull(rand.Reader, coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for i := range shares {
+			shares[i].Y[b] = evaluate(coeffs, shares[i].X)
+		}
+	}
+	clear(coeffs)
+	return shares, nil
+}
+
+// Combine recovers the secret from at least threshold distinct shares. With
+// fewer shares it returns garbage rather than an error, so callers should
+// verify the result.
+func Combine(shares []Share) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("at least 2 shares are required")
+	}
+
+	size := len(shares[0].Y)
+	seen := map[byte]bool{}
+	for _, s := range shares {
+		if s.X == 0 {
+			return nil, errors.New("invalid share index 0")
+		}
+		if seen[s.X] {
+			return nil, fmt.Errorf("share %d given twice", s.X)
+		}
+		if len(s.Y) != size {
+			return nil, errors.New("shares have different lengths")
+		}
+		seen[s.X] = true
+	}
+
+	// Lagrange interpolation at x = 0
+	secret := make([]byte, size)
+	for i, si := range shares {
+		basis := byte(1)
+		for j, sj := range shares {
+			if i != j {
+				basis = mul(basis, div(sj.X, sj.X^si.X))
+			}
+		}
+		for b := range secret {
+			secret[b] ^= mul(si.Y[b], basis)
+		}
+	}
+	return secret, nil
+}
+
+// evaluate computes the polynomial with the given coefficients at x using
+// Horner's method.
+func evaluate(coeffs []byte, x byte) byte {
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coeffs[i]
+	}
+	return y
+}
+
+// mul multiplies in GF(2^8) with the AES polynomial x^8 + x^4 + x^3 + x + 1,
+// without table lookups indexed by secret data.
+func mul(a, b byte) byte {
+	var p byte
+	for range 8 {
+		p ^= -(b & 1) & a
+		carry := -(a >> 7) & 0x1b
+		a = a<<1 ^ carry
+		b >>= 1
+	}
+	return p
+}
+
+// div divides a by b (b != 0) in GF(2^8), using b^-1 = b^254.
+func div(a, b byte) byte {
+	inv := b
+	for range 6 {
+		b = mul(b, b)
+		inv = mul(inv, b)
+	}
+	return mul(a, mul(inv, inv))
+}
@@ -0,0 +1,131 @@
+package shamir
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestSplitCombine(t *testing.T) {
+	tests := []struct {
+		name      string
+		n, k      int
+		use       []int
+		wantMatch bool
+	}{
+		{"threshold of 2", 3, 2, []int{0, 2}, true},
+		{"threshold of 3 in order", 5, 3, []int{0, 1, 2}, true},
+		{"threshold of 3 any order", 5, 3, []int{4, 1, 3}, true},
+		{"more than the threshold", 5, 3, []int{0, 1, 2, 3, 4}, true},
+		{"n equals threshold", 4, 4, []int{3, 2, 1, 0}, true},
+		{"below threshold", 5, 3, []int{0, 1}, false},
+		{"far below threshold", 10, 8, []int{0, 3, 5, 9}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares, err := Split(secret, tt.n, tt.k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(shares) != tt.n {
+				t.Fatalf("got %d shares, want %d", len(shares), tt.n)
+			}
+
+			var subset []Share
+			for _, i := range tt.use {
+				subset = append(subset, shares[i])
+			}
+			got, err := Combine(subset)
+			if err != nil {
+				t.Fatalf("Combine: %v", err)
+			}
+			if bytes.Equal(got, secret) != tt.wantMatch {
+				t.Errorf("Combine of shares %v recovered the secret: %t, want %t", tt.use, !tt.wantMatch, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestCombineCorruptedShare(t *testing.T) {
+	shares, err := Split(secret, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares[1].Y[4] ^= 0x5a
+
+	got, err := Combine(shares[:2])
+	if err != nil {
+		t.Fatalf("Combine: %v", err)
+	}
+	if bytes.Equal(got, secret) {
+		t.Fatal("a corrupted share still recovered the secret")
+	}
+	for i := range got {
+		if i != 4 && got[i] != secret[i] {
+			t.Errorf("byte %d changed although only byte 4 of a share was corrupted", i)
+		}
+	}
+}
+
+func TestSplitRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  []byte
+		n, k    int
+		wantErr string
+	}{
+		{"empty secret", nil, 3, 2, "must not be empty"},
+		{"threshold of 1", secret, 3, 1, "at least 2"},
+		{"fewer shares than threshold", secret, 2, 3, "at least the threshold"},
+		{"too many shares", secret, 256, 2, "at most 255"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Split(tt.secret, tt.n, tt.k)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Split returned %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCombineRejects(t *testing.T) {
+	shares, err := Split(secret, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		shares  []Share
+		wantErr string
+	}{
+		{"single share", shares[:1], "at least 2 shares"},
+		{"duplicate share", []Share{shares[0], shares[0]}, "given twice"},
+		{"index zero", []Share{shares[0], {X: 0, Y: shares[1].Y}}, "index 0"},
+		{"different lengths", []Share{shares[0], {X: 2, Y: shares[1].Y[:3]}}, "different lengths"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Combine(tt.shares)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Combine returned %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFieldArithmetic(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			if got := div(mul(byte(a), byte(b)), byte(b)); got != byte(a) {
+				t.Fatalf("(%d*%d)/%d = %d", a, b, b, got)
+			}
+		}
+	}
+}
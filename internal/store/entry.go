@@ -0,0 +1,66 @@
+package store
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// Entry is a stored token: the encrypted value plus metadata that can be
+// read without a passphrase.
+type Entry struct {
+	// Value is the encrypted token in envelope format.
+	Value string `json:"value"`
+
+	Created time.Time `json:"created,omitzero"`
+	Updated time.Time `json:"updated,omitzero"`
+
+	Description string    `json:"description,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Service     string    `json:"service,omitempty"`
+	Owner       string    `json:"owner,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at,omitzero"`
+
+	// Username is the account the token logs in as, e.g. a registry user,
+	// as opposed to Owner, the person responsible for it.
+	Username string `json:"username,omitempty"`
+
+	// History holds earlier encrypted values, newest first.
+	History []Version `json:"history,omitempty"`
+}
+
+// ExpiresWithin reports whether the entry has an expiry date on or before
+// now+d. Already expired entries are included.
+func (e Entry) ExpiresWithin(now time.Time, d time.Duration) bool {
+	return !e.ExpiresAt.IsZero() && !e.ExpiresAt.After(now.Add(d))
+}
+
+// UnmarshalJSON accepts both the current object form and the bare encrypted
+// string written by releases that stored no metadata.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*e = Entry{Value: value}
+		return nil
+	}
+
+	type plain Entry
+	return json.Unmarshal(data, (*plain)(e))
+}
+
+func (e Entry) clone() Entry {
+	if e.Tags != nil {
+		e.Tags = append([]string(nil), e.Tags...)
+	}
+	if e.History != nil {
+		e.History = append([]Version(nil), e.History...)
+	}
+	return e
+}
+
+func sameEntry(a, b *Entry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,20 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEntryUnmarshalLegacy(t *testing.T) {
+	var secrets Secrets
+	data := `{"old": "enc-old", "new": {"value": "enc-new", "owner": "ops"}}`
+	if err := json.Unmarshal([]byte(data), &secrets); err != nil {
+		t.Fatal(err)
+	}
+	if secrets["old"].Value != "enc-old" {
+		t.Errorf("legacy entry value %q, want enc-old", secrets["old"].Value)
+	}
+	if secrets["new"].Value != "enc-new" || secrets["new"].Owner != "ops" {
+		t.Errorf("entry %+v, want value enc-new and owner ops", secrets["new"])
+	}
+}
@@ -0,0 +1,163 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// FileMode is the permission applied to every file written by the store.
+const FileMode os.FileMode = 0o600
+
+// DefaultPath returns the location of the secrets file.
+func DefaultPath() string {
+	return os.Getenv("HOME") + "/.secrets.json"
+}
+
+// SidecarPath returns the location of a file kept beside the secrets of
+// DefaultBackend, e.g. ~/.secrets.vault.json for "vault". A MemoryBackend
+// keeps its sidecars in a temporary directory, so tests never touch the home
+// directory.
+func SidecarPath(name string) string {
+	if b, ok := DefaultBackend().(interface{ sidecarPath(string) string }); ok {
+		return b.sidecarPath(name)
+	}
+	return sidecar(DefaultPath(), name)
+}
+
+func sidecar(path, name string) string {
+	return strings.TrimSuffix(path, ".json") + "." + name + ".json"
+}
+
+// FileBackend stores secrets as indented JSON in a single file. Writes are
+// atomic and Lock takes an advisory lock on "<path>.lock".
+type FileBackend struct {
+	Path string
+}
+
+// NewFileBackend returns a backend for the secrets file at path.
+func NewFileBackend(path string) *FileBackend {
+	return &FileBackend{Path: path}
+}
+
+// Load reads the secrets file, treating a missing or empty file as having no
+// secrets yet.
+func (b *FileBackend) Load() (Secrets, error) {
+	secrets := Secrets{}
+
+	data, err := os.ReadFile(b.Path)
+	if os.IsNotExist(err) {
+		return secrets, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading secrets file: %w", err)
+	}
+	if len(data) == 0 {
+		return secrets, nil
+	}
+
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("error decoding secrets file: %w", err)
+	}
+	return secrets, nil
+}
+
+// Save atomically replaces the secrets file.
+func (b *FileBackend) Save(secrets Secrets) error {
+	encJSON, err := json.MarshalIndent(secrets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON marshal error: %w", err)
+	}
+	if err := WriteFile(b.Path, encJSON); err != nil {
+		return fmt.Errorf("error writing secrets file: %w", err)
+	}
+	return nil
+}
+
+func (b *FileBackend) sidecarPath(name string) string {
+	return sidecar(b.Path, name)
+}
+
+// Lock takes an exclusive advisory lock on the secrets file.
+func (b *FileBackend) Lock() (func() error, error) {
+	return Lock(b.Path)
+}
+
+// Lock takes an exclusive advisory lock guarding path. The lock is held on a
+// separate "<path>.lock" file because the data file itself is replaced by
+// rename on every write.
+func Lock(path string) (unlock func() error, err error) {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, FileMode)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lock file: %w", err)
+	}
+
+	if err := lockFile(f); err != nil {
+		if cerr := f.Close(); cerr != nil {
+			fmt.Fprintln(os.Stderr, "Warning: failed to close lock file:", cerr)
+		}
+		return nil, fmt.Errorf("error locking %s: %w", path, err)
+	}
+
+	return func() error {
+		uerr := unlockFile(f)
+		if cerr := f.Close(); uerr == nil {
+			uerr = cerr
+		}
+		return uerr
+	}, nil
+}
+
+// WriteFile atomically replaces path with data: it writes a temporary file in
+// the same directory, fsyncs it, sets FileMode and renames it into place.
+func WriteFile(path string, data []byte) (err error) {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(FileMode); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir flushes the directory entry created by a rename. Windows does not
+// support fsync on directories.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err := d.Sync(); err != nil {
+		_ = d.Close()
+		return err
+	}
+	return d.Close()
+}
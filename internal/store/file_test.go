@@ -0,0 +1,115 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFileBackendLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content *string
+		want    Secrets
+		wantErr bool
+	}{
+		{name: "missing file", want: Secrets{}},
+		{name: "empty file", content: ptr(""), want: Secrets{}},
+		{name: "entries", content: ptr(`{"github": {"value": "enc"}}`), want: Secrets{"github": {Value: "enc"}}},
+		{name: "legacy bare values", content: ptr(`{"github": "enc"}`), want: Secrets{"github": {Value: "enc"}}},
+		{name: "corrupt file", content: ptr(`{"github": `), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "secrets.json")
+			if tt.content != nil {
+				if err := os.WriteFile(path, []byte(*tt.content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := NewFileBackend(path).Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load error = %v, want error %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(got) != len(tt.want) {
+				t.Fatalf("Load = %v, want %v", got, tt.want)
+			}
+			for name, entry := range tt.want {
+				if got[name].Value != entry.Value {
+					t.Errorf("entry %q = %q, want %q", name, got[name].Value, entry.Value)
+				}
+			}
+		})
+	}
+}
+
+func TestFileBackendSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.json")
+	if err := os.WriteFile(path, []byte(`{"old": "enc"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewFileBackend(path)
+	if err := b.Save(Secrets{"github": {Value: "enc"}}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := b.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got["github"].Value != "enc" {
+		t.Errorf("Load after Save = %v, want only github", got)
+	}
+
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != FileMode {
+			t.Errorf("secrets file mode %v, want %v", fi.Mode().Perm(), FileMode)
+		}
+	}
+
+	// The temporary file is renamed into place, so nothing is left beside it
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("directory holds %v, want only secrets.json", names)
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestSidecarPath(t *testing.T) {
+	defer SetDefaultBackend(nil)
+
+	SetDefaultBackend(NewFileBackend("/data/team.json"))
+	if got := SidecarPath("vault"); got != "/data/team.vault.json" {
+		t.Errorf("SidecarPath beside a file = %q, want /data/team.vault.json", got)
+	}
+
+	t.Setenv("HOME", "/home/test")
+	mem := NewMemoryBackend(nil)
+	SetDefaultBackend(mem)
+	got := SidecarPath("settings")
+	if filepath.Base(got) != "secrets.settings.json" || strings.HasPrefix(got, "/home/test") {
+		t.Errorf("SidecarPath of a MemoryBackend = %q, want a file outside HOME", got)
+	}
+	if again := SidecarPath("attempts"); filepath.Dir(again) != filepath.Dir(got) {
+		t.Errorf("sidecars of one MemoryBackend in %q and %q, want one directory", filepath.Dir(got), filepath.Dir(again))
+	}
+	os.RemoveAll(filepath.Dir(got))
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultHistoryKeep is the number of earlier values kept per entry unless
+// SetRetention says otherwise.
+const DefaultHistoryKeep = 5
+
+// Version is an earlier encrypted value of an entry. It stays sealed the way
+// it was when it was replaced.
+type Version struct {
+	Value string `json:"value"`
+
+	// Stored is when this value was written; zero for values that predate
+	// timestamps.
+	Stored   time.Time `json:"stored,omitzero"`
+	Replaced time.Time `json:"replaced"`
+}
+
+// Retention bounds the history kept per entry.
+type Retention struct {
+	// Keep is the number of earlier values kept; 0 keeps none.
+	Keep int
+
+	// MaxAge drops values replaced longer ago than this; 0 keeps them
+	// regardless of age.
+	MaxAge time.Duration
+}
+
+var retention = Retention{Keep: DefaultHistoryKeep}
+
+// SetRetention changes the history retention applied by Put and Prune.
+func SetRetention(r Retention) {
+	retention = r
+}
+
+// apply returns history trimmed to r; history is newest first.
+func (r Retention) apply(history []Version, now time.Time) []Version {
+	kept := history[:0:0]
+	for _, v := range history {
+		if len(kept) >= r.Keep {
+			break
+		}
+		if r.MaxAge > 0 && now.Sub(v.Replaced) > r.MaxAge {
+			break
+		}
+		kept = append(kept, v)
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+// Rollback makes version n of name's history (1 is the value replaced most
+// recently) the current value. The value it replaces is kept in history, so
+// a rollback can itself be undone. The change is not persisted until Save.
+func (s *Store) Rollback(name string, n int) error {
+	entry, ok := s.Entry(name)
+	if !ok {
+		return fmt.Errorf("token %q %w", name, ErrNotFound)
+	}
+	if n < 1 || n > len(entry.History) {
+		return fmt.Errorf("token %q has no version %d (history holds %d)", name, n, len(entry.History))
+	}
+
+	now := time.Now().UTC()
+	target := entry.History[n-1]
+	history := append([]Version{{Value: entry.Value, Stored: entry.Updated, Replaced: now}}, entry.History[:n-1]...)
+	entry.History = retention.apply(append(history, entry.History[n:]...), now)
+	entry.Value = target.Value
+	entry.Updated = now
+	s.PutEntry(name, entry)
+	return nil
+}
+
+// Prune applies the current retention to every entry and returns the number
+// of earlier values dropped. The change is not persisted until Save.
+func (s *Store) Prune() int {
+	now := time.Now().UTC()
+	dropped := 0
+	for _, name := range s.List() {
+		entry, _ := s.Entry(name)
+		kept := retention.apply(entry.History, now)
+		if len(kept) == len(entry.History) {
+			continue
+		}
+		dropped += len(entry.History) - len(kept)
+		entry.History = kept
+		s.PutEntry(name, entry)
+	}
+	return dropped
+}
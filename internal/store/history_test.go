@@ -0,0 +1,130 @@
+package store
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	defer SetRetention(Retention{Keep: DefaultHistoryKeep})
+
+	tests := []struct {
+		name      string
+		retention Retention
+		values    []string
+		rollback  int
+		want      string
+		history   []string
+	}{
+		{
+			name:      "replaced values newest first",
+			retention: Retention{Keep: 5},
+			values:    []string{"v1", "v2", "v3"},
+			want:      "v3",
+			history:   []string{"v2", "v1"},
+		},
+		{
+			name:      "keep bounds history",
+			retention: Retention{Keep: 2},
+			values:    []string{"v1", "v2", "v3", "v4"},
+			want:      "v4",
+			history:   []string{"v3", "v2"},
+		},
+		{
+			name:      "keep zero drops history",
+			retention: Retention{Keep: 0},
+			values:    []string{"v1", "v2"},
+			want:      "v2",
+		},
+		{
+			name:      "unchanged value adds no version",
+			retention: Retention{Keep: 5},
+			values:    []string{"v1", "v1"},
+			want:      "v1",
+		},
+		{
+			name:      "rollback keeps the replaced value",
+			retention: Retention{Keep: 5},
+			values:    []string{"v1", "v2", "v3"},
+			rollback:  2,
+			want:      "v1",
+			history:   []string{"v3", "v2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetRetention(tt.retention)
+			b := NewMemoryBackend(nil)
+			for _, value := range tt.values {
+				s, err := Open(b)
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.Put("github", value)
+				if err := s.Save(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			s, err := Open(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.rollback > 0 {
+				if err := s.Rollback("github", tt.rollback); err != nil {
+					t.Fatalf("Rollback: %v", err)
+				}
+			}
+
+			entry, _ := s.Entry("github")
+			if entry.Value != tt.want {
+				t.Errorf("value %q, want %q", entry.Value, tt.want)
+			}
+			var history []string
+			for _, v := range entry.History {
+				history = append(history, v.Value)
+			}
+			if !reflect.DeepEqual(history, tt.history) {
+				t.Errorf("history %v, want %v", history, tt.history)
+			}
+		})
+	}
+}
+
+func TestRollbackOutOfRange(t *testing.T) {
+	s, err := Open(NewMemoryBackend(Secrets{"github": {Value: "v2", History: []Version{{Value: "v1"}}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{0, 2} {
+		if err := s.Rollback("github", n); err == nil {
+			t.Errorf("Rollback(%d) succeeded on a one-version history", n)
+		}
+	}
+	if err := s.Rollback("missing", 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Rollback of a missing token returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestPruneMaxAge(t *testing.T) {
+	defer SetRetention(Retention{Keep: DefaultHistoryKeep})
+
+	now := time.Now().UTC()
+	s, err := Open(NewMemoryBackend(Secrets{"github": {Value: "v3", History: []Version{
+		{Value: "v2", Replaced: now.Add(-24 * time.Hour)},
+		{Value: "v1", Replaced: now.Add(-100 * 24 * time.Hour)},
+	}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetRetention(Retention{Keep: 5, MaxAge: 90 * 24 * time.Hour})
+	if dropped := s.Prune(); dropped != 1 {
+		t.Errorf("Prune dropped %d versions, want 1", dropped)
+	}
+	entry, _ := s.Entry("github")
+	if len(entry.History) != 1 || entry.History[0].Value != "v2" {
+		t.Errorf("history after Prune %+v, want only v2", entry.History)
+	}
+}
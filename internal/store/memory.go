@@ -0,0 +1,69 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// MemoryBackend keeps secrets in memory. It is intended for tests.
+type MemoryBackend struct {
+	lock sync.Mutex
+
+	mu      sync.Mutex
+	secrets Secrets
+
+	// dir holds the sidecar files, created on first use.
+	dir string
+}
+
+// NewMemoryBackend returns a backend pre-populated with a copy of initial.
+func NewMemoryBackend(initial Secrets) *MemoryBackend {
+	return &MemoryBackend{secrets: clone(initial)}
+}
+
+// Load returns a copy of the stored secrets.
+func (b *MemoryBackend) Load() (Secrets, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return clone(b.secrets), nil
+}
+
+// Save replaces the stored secrets with a copy of secrets.
+func (b *MemoryBackend) Save(secrets Secrets) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.secrets = clone(secrets)
+	return nil
+}
+
+// Lock takes the backend's exclusive lock.
+func (b *MemoryBackend) Lock() (func() error, error) {
+	b.lock.Lock()
+	return func() error {
+		b.lock.Unlock()
+		return nil
+	}, nil
+}
+
+// sidecarPath places sidecar files in a temporary directory owned by b.
+func (b *MemoryBackend) sidecarPath(name string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.dir == "" {
+		dir, err := os.MkdirTemp("", "deecli-memory-")
+		if err != nil {
+			dir = os.TempDir()
+		}
+		b.dir = dir
+	}
+	return filepath.Join(b.dir, sidecar("secrets.json", name))
+}
+
+func clone(secrets Secrets) Secrets {
+	out := make(Secrets, len(secrets))
+	for name, entry := range secrets {
+		out[name] = entry.clone()
+	}
+	return out
+}
@@ -0,0 +1,316 @@
+// Package store is the single code path for reading and writing
+// ~/.secrets.json. A Store is an in-memory view of the secrets loaded from a
+// Backend; Save re-reads the backend under its lock and applies only the
+// changes made through the Store, so concurrent deecli invocations do not lose
+// each other's updates.
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secrets maps token names to entries.
+type Secrets map[string]Entry
+
+// Backend persists Secrets.
+type Backend interface {
+	// Load returns the stored secrets; a backend with nothing stored yet
+	// returns an empty map.
+	Load() (Secrets, error)
+
+	// Save replaces the stored secrets.
+	Save(Secrets) error
+
+	// Lock takes an exclusive lock held across a Load/Save cycle.
+	Lock() (unlock func() error, err error)
+}
+
+var (
+	defaultMu      sync.Mutex
+	defaultBackend Backend
+)
+
+// SetDefaultBackend overrides the backend used by OpenDefault, for example
+// with a MemoryBackend in tests. Passing nil restores the file backend.
+func SetDefaultBackend(b Backend) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultBackend = b
+}
+
+// DefaultBackend returns the backend used by OpenDefault.
+func DefaultBackend() Backend {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultBackend != nil {
+		return defaultBackend
+	}
+	return NewFileBackend(DefaultPath())
+}
+
+// ConflictError is returned by Save when an entry changed in the backend
+// after the Store was opened.
+type ConflictError struct {
+	Names []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s modified by another deecli process; try again", quoteList(e.Names))
+}
+
+// ErrNotFound is returned for names that are not in the store.
+var ErrNotFound = errors.New("not found in secrets")
+
+// Store is an in-memory view of the secrets held by a Backend.
+type Store struct {
+	backend Backend
+	secrets Secrets
+
+	// loaded records each changed entry as it was when read, nil meaning
+	// absent, so Save can detect conflicting writers. changes holds the new
+	// entries, nil meaning deleted.
+	loaded  map[string]*Entry
+	changes map[string]*Entry
+
+	onSave func(saved Secrets, changed []string) error
+}
+
+// Open loads the secrets held by b.
+func Open(b Backend) (*Store, error) {
+	secrets, err := b.Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Store{
+		backend: b,
+		secrets: secrets,
+		loaded:  map[string]*Entry{},
+		changes: map[string]*Entry{},
+	}, nil
+}
+
+// OpenDefault loads the secrets held by DefaultBackend.
+func OpenDefault() (*Store, error) {
+	return Open(DefaultBackend())
+}
+
+// Get returns the encrypted value stored under name.
+func (s *Store) Get(name string) (string, bool) {
+	entry, ok := s.secrets[name]
+	return entry.Value, ok
+}
+
+// Lookup is like Get but returns an error wrapping ErrNotFound if name is
+// missing.
+func (s *Store) Lookup(name string) (string, error) {
+	entry, ok := s.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("token %q %w", name, ErrNotFound)
+	}
+	return entry.Value, nil
+}
+
+// Entry returns a copy of the entry stored under name, including metadata.
+func (s *Store) Entry(name string) (Entry, bool) {
+	entry, ok := s.secrets[name]
+	return entry.clone(), ok
+}
+
+// Put stores an encrypted value under name, keeping the existing metadata
+// and stamping the created and updated times. A replaced value is kept in
+// the entry's history, subject to the retention set by SetRetention. The
+// change is not persisted until Save.
+func (s *Store) Put(name, value string) {
+	now := time.Now().UTC()
+
+	entry, ok := s.Entry(name)
+	if !ok {
+		entry.Created = now
+	}
+	if ok && entry.Value != value {
+		entry.History = append([]Version{{Value: entry.Value, Stored: entry.Updated, Replaced: now}}, entry.History...)
+		entry.History = retention.apply(entry.History, now)
+	}
+	entry.Value = value
+	entry.Updated = now
+	s.PutEntry(name, entry)
+}
+
+// Reseal replaces the value stored under name with a re-encryption of the
+// same token, without recording history or touching the timestamps. The
+// change is not persisted until Save.
+func (s *Store) Reseal(name, value string) {
+	entry, ok := s.Entry(name)
+	if !ok {
+		return
+	}
+	entry.Value = value
+	s.PutEntry(name, entry)
+}
+
+// PutEntry stores entry under name as-is. The change is not persisted until
+// Save.
+func (s *Store) PutEntry(name string, entry Entry) {
+	s.record(name)
+	entry = entry.clone()
+	s.secrets[name] = entry
+	s.changes[name] = &entry
+}
+
+// Rename moves the entry stored under oldName to newName. It refuses to
+// replace an existing entry unless force is set.
+func (s *Store) Rename(oldName, newName string, force bool) error {
+	if err := s.Copy(oldName, newName, force); err != nil {
+		return err
+	}
+	entry, _ := s.Entry(newName)
+	entry.Created = s.secrets[oldName].Created
+	s.PutEntry(newName, entry)
+	s.Delete(oldName)
+	return nil
+}
+
+// Copy duplicates the entry stored under src as dst, with a fresh created
+// time. It refuses to replace an existing entry unless force is set.
+func (s *Store) Copy(src, dst string, force bool) error {
+	entry, ok := s.Entry(src)
+	if !ok {
+		return fmt.Errorf("token %q %w", src, ErrNotFound)
+	}
+	if src == dst {
+		return fmt.Errorf("source and destination are both %q", src)
+	}
+	if _, exists := s.secrets[dst]; exists && !force {
+		return fmt.Errorf("token %q already exists", dst)
+	}
+
+	now := time.Now().UTC()
+	entry.Created = now
+	entry.Updated = now
+	s.PutEntry(dst, entry)
+	return nil
+}
+
+// Delete removes name and reports whether it existed. The change is not
+// persisted until Save.
+func (s *Store) Delete(name string) bool {
+	if _, ok := s.secrets[name]; !ok {
+		return false
+	}
+	s.record(name)
+	delete(s.secrets, name)
+	s.changes[name] = nil
+	return true
+}
+
+// List returns the stored names in sorted order.
+func (s *Store) List() []string {
+	names := make([]string, 0, len(s.secrets))
+	for name := range s.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OnSave registers fn to be called by Save with the secrets as written and
+// the sorted names the save changed, while the backend lock is still held. An
+// error from fn is returned by Save although the secrets have already been
+// written.
+func (s *Store) OnSave(fn func(saved Secrets, changed []string) error) {
+	s.onSave = fn
+}
+
+// Save persists the changes made since Open. The backend is re-read under
+// its lock and only changed names are written, so entries added or removed
+// by other processes in the meantime are kept. If another process changed
+// one of the same names, nothing is written and a *ConflictError is returned.
+func (s *Store) Save() error {
+	if len(s.changes) == 0 {
+		return nil
+	}
+
+	unlock, err := s.backend.Lock()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if uerr := unlock(); uerr != nil {
+			fmt.Fprintln(os.Stderr, "Warning: failed to release lock:", uerr)
+		}
+	}()
+
+	current, err := s.backend.Load()
+	if err != nil {
+		return err
+	}
+
+	var conflicts []string
+	for name := range s.changes {
+		var onDisk *Entry
+		if entry, ok := current[name]; ok {
+			onDisk = &entry
+		}
+		if !sameEntry(onDisk, s.loaded[name]) {
+			conflicts = append(conflicts, name)
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return &ConflictError{Names: conflicts}
+	}
+
+	for name, entry := range s.changes {
+		if entry == nil {
+			delete(current, name)
+		} else {
+			current[name] = *entry
+		}
+	}
+	if err := s.backend.Save(current); err != nil {
+		return err
+	}
+
+	changed := make([]string, 0, len(s.changes))
+	for name := range s.changes {
+		changed = append(changed, name)
+	}
+	sort.Strings(changed)
+
+	s.secrets = current
+	s.loaded = map[string]*Entry{}
+	s.changes = map[string]*Entry{}
+	if s.onSave != nil {
+		return s.onSave(current, changed)
+	}
+	return nil
+}
+
+func (s *Store) record(name string) {
+	if _, ok := s.changes[name]; ok {
+		return
+	}
+	if entry, ok := s.secrets[name]; ok {
+		entry = entry.clone()
+		s.loaded[name] = &entry
+	} else {
+		s.loaded[name] = nil
+	}
+}
+
+func quoteList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	if len(quoted) == 1 {
+		return "token " + quoted[0] + " was"
+	}
+	return "tokens " + strings.Join(quoted, ", ") + " were"
+}
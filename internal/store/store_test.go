@@ -0,0 +1,167 @@
+package store
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSave(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial Secrets
+		change  func(*Store)
+		want    map[string]string
+	}{
+		{
+			name:   "put new entry",
+			change: func(s *Store) { s.Put("github", "v1") },
+			want:   map[string]string{"github": "v1"},
+		},
+		{
+			name:    "replace entry",
+			initial: Secrets{"github": {Value: "v1"}},
+			change:  func(s *Store) { s.Put("github", "v2") },
+			want:    map[string]string{"github": "v2"},
+		},
+		{
+			name:    "delete entry",
+			initial: Secrets{"github": {Value: "v1"}, "stripe": {Value: "s1"}},
+			change:  func(s *Store) { s.Delete("github") },
+			want:    map[string]string{"stripe": "s1"},
+		},
+		{
+			name:    "rename entry",
+			initial: Secrets{"github": {Value: "v1"}},
+			change: func(s *Store) {
+				if err := s.Rename("github", "gh", false); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: map[string]string{"gh": "v1"},
+		},
+		{
+			name:    "copy entry",
+			initial: Secrets{"github": {Value: "v1"}},
+			change: func(s *Store) {
+				if err := s.Copy("github", "gh", false); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: map[string]string{"github": "v1", "gh": "v1"},
+		},
+		{
+			name:    "no changes",
+			initial: Secrets{"github": {Value: "v1"}},
+			change:  func(s *Store) {},
+			want:    map[string]string{"github": "v1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewMemoryBackend(tt.initial)
+			s, err := Open(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.change(s)
+			if err := s.Save(); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			saved, _ := b.Load()
+			got := map[string]string{}
+			for name, entry := range saved {
+				got[name] = entry.Value
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("saved %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		ours     func(*Store)
+		theirs   func(*Store)
+		conflict []string
+		want     map[string]string
+	}{
+		{
+			name:   "different names merge",
+			ours:   func(s *Store) { s.Put("github", "ours") },
+			theirs: func(s *Store) { s.Put("stripe", "theirs") },
+			want:   map[string]string{"github": "ours", "stripe": "theirs", "aws": "a1"},
+		},
+		{
+			name:     "same name conflicts",
+			ours:     func(s *Store) { s.Put("aws", "ours") },
+			theirs:   func(s *Store) { s.Put("aws", "theirs") },
+			conflict: []string{"aws"},
+			want:     map[string]string{"aws": "theirs"},
+		},
+		{
+			name:     "both add the same name",
+			ours:     func(s *Store) { s.Put("github", "ours") },
+			theirs:   func(s *Store) { s.Put("github", "theirs") },
+			conflict: []string{"github"},
+			want:     map[string]string{"aws": "a1", "github": "theirs"},
+		},
+		{
+			name:     "delete of a changed entry conflicts",
+			ours:     func(s *Store) { s.Delete("aws") },
+			theirs:   func(s *Store) { s.Put("aws", "theirs") },
+			conflict: []string{"aws"},
+			want:     map[string]string{"aws": "theirs"},
+		},
+		{
+			name:   "delete kept by the other process",
+			ours:   func(s *Store) { s.Delete("aws") },
+			theirs: func(s *Store) { s.Put("github", "theirs") },
+			want:   map[string]string{"github": "theirs"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewMemoryBackend(Secrets{"aws": {Value: "a1"}})
+			ours, err := Open(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			theirs, err := Open(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.theirs(theirs)
+			if err := theirs.Save(); err != nil {
+				t.Fatalf("first Save: %v", err)
+			}
+			tt.ours(ours)
+			err = ours.Save()
+
+			var conflict *ConflictError
+			switch {
+			case tt.conflict == nil && err != nil:
+				t.Fatalf("Save: %v", err)
+			case tt.conflict != nil && !errors.As(err, &conflict):
+				t.Fatalf("Save returned %v, want a ConflictError", err)
+			case tt.conflict != nil && !reflect.DeepEqual(conflict.Names, tt.conflict):
+				t.Errorf("conflicting names %v, want %v", conflict.Names, tt.conflict)
+			}
+
+			saved, _ := b.Load()
+			got := map[string]string{}
+			for name, entry := range saved {
+				got[name] = entry.Value
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("saved %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
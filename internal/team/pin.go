@@ -0,0 +1,137 @@
+package team
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/deeragoo/deecli/internal/keys"
+	"github.com/deeragoo/deecli/internal/store"
+)
+
+// ErrKeyChanged is returned by Unlock when the data key differs from the one
+// pinned the last time this vault was used on this machine.
+var ErrKeyChanged = errors.New("the team key changed since you last used this vault; a member may have been removed, " +
+	"or someone replaced the key to read new secrets. Confirm with a teammate, then run 'deecli team sign'")
+
+// Pin is what this machine trusts about one team vault: a fingerprint of its
+// data key and the members it was last seen with.
+//
+// The MAC only proves that whoever wrote the file held a data key, and the
+// file itself is enough to make a new one: anyone who can push could wrap a
+// fresh key for every member and sign with it. Members would then unlock
+// without complaint and encrypt new secrets the writer can read. Pinning the
+// key on first use turns that into ErrKeyChanged.
+type Pin struct {
+	Key     string            `json:"key"`
+	Members map[string]string `json:"members"`
+}
+
+// PinPath returns the location of the pins of every team vault used on this
+// machine.
+func PinPath() string {
+	return filepath.Join(keys.Dir(), "team-pins.json")
+}
+
+// LoadPin returns the pin recorded for the vault at path, if any.
+func LoadPin(path string) (*Pin, error) {
+	pins, err := loadPins()
+	if err != nil {
+		return nil, err
+	}
+	pin, ok := pins[path]
+	if !ok {
+		return nil, nil
+	}
+	return &pin, nil
+}
+
+// Pinned returns the pin recorded for v on this machine, if any.
+func (v *Vault) Pinned() (*Pin, error) {
+	if v.path == "" {
+		return nil, nil
+	}
+	return LoadPin(v.path)
+}
+
+// pin records the data key and members of v as trusted for its path.
+func (v *Vault) pin(dek []byte) error {
+	if v.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(keys.Dir(), 0o700); err != nil {
+		return err
+	}
+	unlock, err := store.Lock(PinPath())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if uerr := unlock(); uerr != nil {
+			fmt.Fprintln(os.Stderr, "Warning: failed to release lock:", uerr)
+		}
+	}()
+
+	pins, err := loadPins()
+	if err != nil {
+		return err
+	}
+	pin := Pin{Key: fingerprint(dek), Members: map[string]string{}}
+	for name, m := range v.Members {
+		pin.Members[name] = m.PublicKey
+	}
+	pins[v.path] = pin
+
+	data, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON marshal error: %w", err)
+	}
+	if err := store.WriteFile(PinPath(), data); err != nil {
+		return fmt.Errorf("error writing team pins: %w", err)
+	}
+	return nil
+}
+
+// checkPin compares dek with the key pinned for v, pinning it on first use.
+func (v *Vault) checkPin(dek []byte) error {
+	if v.path == "" {
+		return nil
+	}
+	pin, err := LoadPin(v.path)
+	if err != nil {
+		return err
+	}
+	if pin == nil {
+		return v.pin(dek)
+	}
+	if !hmac.Equal([]byte(pin.Key), []byte(fingerprint(dek))) {
+		return ErrKeyChanged
+	}
+	return nil
+}
+
+func loadPins() (map[string]Pin, error) {
+	pins := map[string]Pin{}
+	data, err := os.ReadFile(PinPath())
+	if os.IsNotExist(err) {
+		return pins, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading team pins: %w", err)
+	}
+	if err := json.Unmarshal(data, &pins); err != nil {
+		return nil, fmt.Errorf("error decoding team pins: %w", err)
+	}
+	return pins, nil
+}
+
+// fingerprint identifies dek without revealing it.
+func fingerprint(dek []byte) string {
+	h := hmac.New(sha256.New, dek)
+	h.Write([]byte("deecli-team-pin-v1"))
+	return hex.EncodeToString(h.Sum(nil))
+}
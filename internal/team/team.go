@@ -0,0 +1,394 @@
+// Package team implements the multi-recipient vault kept at .deecli/vault.json
+// inside a project repository. Secrets are sealed with AES-GCM under a random
+// data key, and the data key is wrapped separately for each member's X25519
+// public key, so the file is safe to commit.
+//
+// Anyone who can push to the repository can edit the file, so the member list
+// and the secrets are covered by a MAC keyed from the data key, and every
+// secret is bound to its name. The MAC cannot stop a writer from replacing the
+// data key altogether, so each machine also pins the key on first use; see
+// Pin. Unlock checks both before the key is used.
+package team
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/deeragoo/deecli/internal/envelope"
+	"github.com/deeragoo/deecli/internal/keys"
+	"github.com/deeragoo/deecli/internal/store"
+)
+
+// FormatVersion is the team vault version written by Save. Version 1 vaults
+// carry no MAC and must be signed with Upgrade before Unlock accepts them.
+const FormatVersion = 2
+
+const keySize = 32
+
+// RelPath is the location of the team vault relative to the project root.
+var RelPath = filepath.Join(".deecli", "vault.json")
+
+// ErrNotFound is returned by Find when no team vault exists above a directory.
+var ErrNotFound = errors.New("no team vault found (run 'deecli team init' in the project root)")
+
+// ErrNotMember is returned by Unlock when the key is not one of the members.
+var ErrNotMember = errors.New("your public key is not a member of this team vault")
+
+// ErrUnsigned is returned by Unlock for a vault without a MAC.
+var ErrUnsigned = errors.New("team vault is not signed; review 'deecli team members' and run 'deecli team sign'")
+
+// ErrForged is returned by Unlock when the MAC does not match, i.e. members or
+// secrets were changed by someone without the data key.
+var ErrForged = errors.New("team vault fails its MAC: members or secrets were changed without the team key")
+
+// Member is one recipient of the data key.
+type Member struct {
+	PublicKey string `json:"public_key"`
+
+	// Key is the data key sealed to PublicKey with an anonymous NaCl box.
+	Key string `json:"key"`
+}
+
+// Vault is the on-disk team vault.
+type Vault struct {
+	Version int               `json:"version"`
+	Members map[string]Member `json:"members"`
+
+	// Secrets maps names to envelopes sealed under the data key.
+	Secrets map[string]string `json:"secrets"`
+
+	// MAC authenticates the version, members and secrets under a key
+	// derived from the data key.
+	MAC string `json:"mac,omitempty"`
+
+	// path is the absolute location of the file, under which its key is
+	// pinned; signedKey is the data key of the last change, pinned by Save.
+	path      string
+	signedKey []byte
+}
+
+// Find looks for a team vault in dir and its parents.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, RelPath)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ErrNotFound
+		}
+		dir = parent
+	}
+}
+
+// Create starts a team vault with a fresh data key and a single member. It
+// refuses to replace an existing file.
+func Create(path, name string, pub keys.PublicKey) (*Vault, []byte, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, nil, fmt.Errorf("team vault already exists at %s", path)
+	}
+
+	dek := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return nil, nil, err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	v := &Vault{Version: FormatVersion, Members: map[string]Member{}, Secrets: map[string]string{}, path: abs}
+	if err := v.AddMember(dek, name, pub); err != nil {
+		return nil, nil, err
+	}
+	return v, dek, nil
+}
+
+// Load reads the team vault at path.
+func Load(path string) (*Vault, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, fmt.Errorf("error reading team vault: %w", err)
+	}
+
+	v := &Vault{path: abs}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, fmt.Errorf("error decoding team vault: %w", err)
+	}
+	if v.Version != 1 && v.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported team vault version %d", v.Version)
+	}
+	if v.Members == nil {
+		v.Members = map[string]Member{}
+	}
+	if v.Secrets == nil {
+		v.Secrets = map[string]string{}
+	}
+	return v, nil
+}
+
+// Save writes the team vault to path, creating its directory if needed, and
+// pins the data key of the last change made through v.
+func (v *Vault) Save(path string) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON marshal error: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := store.WriteFile(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing team vault: %w", err)
+	}
+	if v.signedKey == nil {
+		return nil
+	}
+	if v.path, err = filepath.Abs(path); err != nil {
+		return err
+	}
+	return v.pin(v.signedKey)
+}
+
+// Unlock returns the data key using the member entry matching pub, after
+// checking the MAC with it and the key against the one pinned on this
+// machine. The first Unlock of a vault pins its key.
+func (v *Vault) Unlock(pub keys.PublicKey, priv *[32]byte) ([]byte, error) {
+	dek, err := v.UnlockUnverified(pub, priv)
+	if err != nil {
+		return nil, err
+	}
+	if v.MAC == "" {
+		return nil, ErrUnsigned
+	}
+	mac, err := hex.DecodeString(v.MAC)
+	if err != nil || v.Version != FormatVersion || !hmac.Equal(mac, v.mac(dek)) {
+		return nil, ErrForged
+	}
+	if err := v.checkPin(dek); err != nil {
+		return nil, err
+	}
+	return dek, nil
+}
+
+// UnlockUnverified is like Unlock but skips the MAC and the pin. It is only
+// meant for Upgrade, after the members have been reviewed.
+func (v *Vault) UnlockUnverified(pub keys.PublicKey, priv *[32]byte) ([]byte, error) {
+	for _, m := range v.Members {
+		mk, err := keys.ParsePublicKey(m.PublicKey)
+		if err != nil || mk.Key != pub.Key {
+			continue
+		}
+		wrapped, err := base64.StdEncoding.DecodeString(m.Key)
+		if err != nil {
+			return nil, errors.New("malformed wrapped key")
+		}
+		dek, ok := box.OpenAnonymous(nil, wrapped, &pub.Key, priv)
+		if !ok || len(dek) != keySize {
+			return nil, errors.New("cannot unwrap the team key with your private key")
+		}
+		return dek, nil
+	}
+	return nil, ErrNotMember
+}
+
+// MemberNames returns the member names, sorted.
+func (v *Vault) MemberNames() []string {
+	return slices.Sorted(maps.Keys(v.Members))
+}
+
+// Names returns the secret names, sorted.
+func (v *Vault) Names() []string {
+	return slices.Sorted(maps.Keys(v.Secrets))
+}
+
+// AddMember wraps dek for pub and records it under name.
+func (v *Vault) AddMember(dek []byte, name string, pub keys.PublicKey) error {
+	if _, exists := v.Members[name]; exists {
+		return fmt.Errorf("member %q already exists", name)
+	}
+	wrapped, err := box.SealAnonymous(nil, dek, &pub.Key, rand.Reader)
+	if err != nil {
+		return err
+	}
+	v.Members[name] = Member{PublicKey: pub.String(), Key: base64.StdEncoding.EncodeToString(wrapped)}
+	v.sign(dek)
+	return nil
+}
+
+// RemoveMember drops name and rotates the data key: every secret is
+// re-encrypted and the new key is wrapped for the remaining members, so the
+// removed member's copy of the old key opens nothing new. It returns the new
+// data key.
+func (v *Vault) RemoveMember(dek []byte, name string) ([]byte, error) {
+	if _, exists := v.Members[name]; !exists {
+		return nil, fmt.Errorf("no member named %q", name)
+	}
+	if len(v.Members) == 1 {
+		return nil, errors.New("cannot remove the last member")
+	}
+
+	next := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, next); err != nil {
+		return nil, err
+	}
+
+	secrets := make(map[string]string, len(v.Secrets))
+	for secret, sealed := range v.Secrets {
+		plaintext, err := open(secret, sealed, dek)
+		if err != nil {
+			return nil, fmt.Errorf("error decrypting %q: %w", secret, err)
+		}
+		if secrets[secret], err = envelope.SealWithKeyFor(secret, plaintext, next); err != nil {
+			return nil, err
+		}
+	}
+
+	members := make(map[string]Member, len(v.Members)-1)
+	for member, m := range v.Members {
+		if member == name {
+			continue
+		}
+		pub, err := keys.ParsePublicKey(m.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("member %q: %w", member, err)
+		}
+		wrapped, err := box.SealAnonymous(nil, next, &pub.Key, rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		members[member] = Member{PublicKey: m.PublicKey, Key: base64.StdEncoding.EncodeToString(wrapped)}
+	}
+
+	v.Secrets, v.Members = secrets, members
+	v.sign(next)
+	return next, nil
+}
+
+// Get decrypts the secret stored under name.
+func (v *Vault) Get(dek []byte, name string) (string, error) {
+	sealed, ok := v.Secrets[name]
+	if !ok {
+		return "", fmt.Errorf("secret %q not found in team vault", name)
+	}
+	plaintext, err := open(name, sealed, dek)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting %q: %w", name, err)
+	}
+	return string(plaintext), nil
+}
+
+// Set encrypts value under the data key and stores it as name.
+func (v *Vault) Set(dek []byte, name, value string) error {
+	sealed, err := envelope.SealWithKeyFor(name, []byte(value), dek)
+	if err != nil {
+		return err
+	}
+	v.Secrets[name] = sealed
+	v.sign(dek)
+	return nil
+}
+
+// Delete removes the secret stored under name and reports whether it
+// existed.
+func (v *Vault) Delete(dek []byte, name string) bool {
+	if _, ok := v.Secrets[name]; !ok {
+		return false
+	}
+	delete(v.Secrets, name)
+	v.sign(dek)
+	return true
+}
+
+// Upgrade binds every secret to its name and signs the vault as it stands;
+// Save then pins its key. Callers must have the members reviewed first, since
+// whatever the file holds becomes trusted.
+func (v *Vault) Upgrade(dek []byte) error {
+	for name, sealed := range v.Secrets {
+		env, err := envelope.Parse(sealed)
+		if err != nil {
+			return fmt.Errorf("secret %q: %w", name, err)
+		}
+		if env.Bound() {
+			continue
+		}
+		plaintext, err := env.OpenWithKey(dek)
+		if err != nil {
+			return fmt.Errorf("error decrypting %q: %w", name, err)
+		}
+		if v.Secrets[name], err = envelope.SealWithKeyFor(name, plaintext, dek); err != nil {
+			return err
+		}
+	}
+	v.sign(dek)
+	return nil
+}
+
+// sign sets the version and recomputes the MAC under dek, which Save pins.
+func (v *Vault) sign(dek []byte) {
+	v.Version = FormatVersion
+	v.MAC = hex.EncodeToString(v.mac(dek))
+	v.signedKey = dek
+}
+
+// mac authenticates the version, the members and their wrapped keys, and the
+// secrets, in sorted order and length-prefixed.
+func (v *Vault) mac(dek []byte) []byte {
+	kdf := hmac.New(sha256.New, dek)
+	kdf.Write([]byte("deecli-team-v1"))
+
+	h := hmac.New(sha256.New, kdf.Sum(nil))
+	writeField(h, []byte(fmt.Sprint(v.Version)))
+	writeField(h, []byte(fmt.Sprint(len(v.Members))))
+	for _, name := range v.MemberNames() {
+		writeField(h, []byte(name))
+		writeField(h, []byte(v.Members[name].PublicKey))
+		writeField(h, []byte(v.Members[name].Key))
+	}
+	writeField(h, []byte(fmt.Sprint(len(v.Secrets))))
+	for _, name := range v.Names() {
+		writeField(h, []byte(name))
+		writeField(h, []byte(v.Secrets[name]))
+	}
+	return h.Sum(nil)
+}
+
+// writeField writes b length-prefixed, so adjacent fields cannot be
+// re-split.
+func writeField(h hash.Hash, b []byte) {
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(len(b)))
+	h.Write(n[:])
+	h.Write(b)
+}
+
+func open(name, sealed string, dek []byte) ([]byte, error) {
+	env, err := envelope.Parse(sealed)
+	if err != nil {
+		return nil, err
+	}
+	return env.OpenWithKeyFor(name, dek)
+}
@@ -0,0 +1,253 @@
+package team
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/deeragoo/deecli/internal/keys"
+)
+
+type member struct {
+	pub  keys.PublicKey
+	priv *[32]byte
+}
+
+func newMember(t *testing.T) member {
+	t.Helper()
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return member{keys.PublicKey{Key: *pub}, priv}
+}
+
+// newVault saves a vault shared by alice and bob with one secret, pinned on
+// this machine, and returns its path.
+func newVault(t *testing.T, alice, bob member) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), ".deecli", "vault.json")
+
+	v, dek, err := Create(path, "alice", alice.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.AddMember(dek, "bob", bob.pub); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Set(dek, "db_password", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUnlock(t *testing.T) {
+	alice, bob, mallory := newMember(t), newMember(t), newMember(t)
+
+	tests := []struct {
+		name    string
+		tamper  func(t *testing.T, v *Vault)
+		wantErr error
+	}{
+		{
+			name:   "untouched",
+			tamper: func(t *testing.T, v *Vault) {},
+		},
+		{
+			name: "member slipped in",
+			tamper: func(t *testing.T, v *Vault) {
+				v.Members["mallory"] = Member{PublicKey: mallory.pub.String(), Key: v.Members["bob"].Key}
+			},
+			wantErr: ErrForged,
+		},
+		{
+			name: "secrets swapped",
+			tamper: func(t *testing.T, v *Vault) {
+				v.Secrets["api_key"] = v.Secrets["db_password"]
+			},
+			wantErr: ErrForged,
+		},
+		{
+			name:    "MAC removed",
+			tamper:  func(t *testing.T, v *Vault) { v.MAC = "" },
+			wantErr: ErrUnsigned,
+		},
+		{
+			name: "data key replaced",
+			tamper: func(t *testing.T, v *Vault) {
+				// What anyone with write access can do: wrap a fresh key
+				// for every member, add themselves and sign.
+				dek := make([]byte, keySize)
+				rand.Read(dek)
+				for name, m := range v.Members {
+					pub, err := keys.ParsePublicKey(m.PublicKey)
+					if err != nil {
+						t.Fatal(err)
+					}
+					wrapped, err := box.SealAnonymous(nil, dek, &pub.Key, rand.Reader)
+					if err != nil {
+						t.Fatal(err)
+					}
+					v.Members[name] = Member{PublicKey: m.PublicKey, Key: base64.StdEncoding.EncodeToString(wrapped)}
+				}
+				if err := v.AddMember(dek, "mallory", mallory.pub); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: ErrKeyChanged,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := newVault(t, alice, bob)
+			v, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(t, v)
+			// Write the tampered file the way an attacker would, without
+			// touching this machine's pins.
+			v.signedKey = nil
+			if err := v.Save(path); err != nil {
+				t.Fatal(err)
+			}
+
+			v, err = Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dek, err := v.Unlock(bob.pub, bob.priv)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Unlock returned %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got, err := v.Get(dek, "db_password"); err != nil || got != "hunter2" {
+				t.Errorf("Get = %q, %v; want %q", got, err, "hunter2")
+			}
+		})
+	}
+}
+
+func TestUnlockNotMember(t *testing.T) {
+	alice, bob, mallory := newMember(t), newMember(t), newMember(t)
+	v, err := Load(newVault(t, alice, bob))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Unlock(mallory.pub, mallory.priv); !errors.Is(err, ErrNotMember) {
+		t.Errorf("Unlock returned %v, want ErrNotMember", err)
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	alice, bob := newMember(t), newMember(t)
+	path := newVault(t, alice, bob)
+
+	v, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := v.Unlock(alice.pub, alice.priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := v.RemoveMember(old, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.Members["bob"]; ok {
+		t.Error("bob is still a member")
+	}
+	if _, err := v.Unlock(bob.pub, bob.priv); !errors.Is(err, ErrNotMember) {
+		t.Errorf("removed member unlock returned %v, want ErrNotMember", err)
+	}
+	if _, err := v.Get(old, "db_password"); err == nil {
+		t.Error("the old data key still opens secrets")
+	}
+
+	// The remover's own Save pinned the rotated key.
+	dek, err := v.Unlock(alice.pub, alice.priv)
+	if err != nil {
+		t.Fatalf("Unlock after rotation: %v", err)
+	}
+	if string(dek) != string(next) {
+		t.Error("Unlock returned a different key than RemoveMember")
+	}
+	if got, err := v.Get(dek, "db_password"); err != nil || got != "hunter2" {
+		t.Errorf("Get = %q, %v; want %q", got, err, "hunter2")
+	}
+}
+
+func TestSignRepins(t *testing.T) {
+	alice, bob := newMember(t), newMember(t)
+	path := newVault(t, alice, bob)
+
+	// Another machine rotates the key; this one has not seen it yet.
+	v, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dek, err := v.Unlock(alice.pub, alice.priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.RemoveMember(dek, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	v.signedKey = nil
+	if err := v.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Unlock(bob.pub, bob.priv); !errors.Is(err, ErrKeyChanged) {
+		t.Fatalf("Unlock returned %v, want ErrKeyChanged", err)
+	}
+	pin, err := v.Pinned()
+	if err != nil || pin == nil {
+		t.Fatalf("Pinned = %v, %v", pin, err)
+	}
+	if _, ok := pin.Members["alice"]; !ok {
+		t.Error("pin lost the member it was taken with")
+	}
+
+	// What 'deecli team sign' does once the change is confirmed.
+	dek, err = v.UnlockUnverified(bob.pub, bob.priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Upgrade(dek); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if v, err = Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Unlock(bob.pub, bob.priv); err != nil {
+		t.Errorf("Unlock after sign: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package vault
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/deeragoo/deecli/internal/store"
+)
+
+// Index lists every name in ~/.secrets.json with a digest of its encrypted
+// value, under a MAC keyed from the DEK. Entry names are already bound to
+// their ciphertexts; the index additionally reveals entries that were
+// deleted, added or swapped for other ciphertexts behind deecli's back.
+type Index struct {
+	Signed  time.Time         `json:"signed"`
+	Entries map[string]string `json:"entries"`
+	MAC     string            `json:"mac"`
+}
+
+// IndexReport is the outcome of CheckIndex.
+type IndexReport struct {
+	// Unsigned is set when the vault has no index yet.
+	Unsigned bool
+
+	// Forged is set when the index itself fails its MAC; the lists below
+	// are then not computed.
+	Forged bool
+
+	// Missing names are in the index but not in the secrets, Added names
+	// the other way round, and Changed names hold a different value than
+	// the one signed.
+	Missing, Added, Changed []string
+}
+
+// OK reports whether the secrets match a valid index.
+func (r *IndexReport) OK() bool {
+	return !r.Unsigned && !r.Forged && len(r.Missing)+len(r.Added)+len(r.Changed) == 0
+}
+
+// ErrIndexForged is returned by UpdateIndex when the index on disk fails its
+// MAC, so its digests cannot be carried forward.
+var ErrIndexForged = errors.New("the vault index fails its MAC and was not updated; run 'deecli secrets verify'")
+
+// SignIndex records secrets, as they stand, in the vault index under dek.
+func SignIndex(secrets store.Secrets, dek []byte) error {
+	v, err := Load()
+	if err != nil {
+		return err
+	}
+	entries := map[string]string{}
+	for name, entry := range secrets {
+		entries[name] = digest(entry.Value)
+	}
+	return v.sign(entries, dek)
+}
+
+// UpdateIndex re-signs the vault index after a save of secrets that changed
+// the given names. Every other name keeps the digest it was signed with, so
+// an entry deleted, added or replaced outside deecli is still reported by
+// CheckIndex rather than signed along with an unrelated change. A vault
+// without an index yet is signed as secrets stand.
+func UpdateIndex(secrets store.Secrets, changed []string, dek []byte) error {
+	v, err := Load()
+	if err != nil {
+		return err
+	}
+	if v.Index == nil {
+		return SignIndex(secrets, dek)
+	}
+	if !v.Index.valid(dek) {
+		return ErrIndexForged
+	}
+
+	entries := maps.Clone(v.Index.Entries)
+	if entries == nil {
+		entries = map[string]string{}
+	}
+	for _, name := range changed {
+		if entry, ok := secrets[name]; ok {
+			entries[name] = digest(entry.Value)
+		} else {
+			delete(entries, name)
+		}
+	}
+	return v.sign(entries, dek)
+}
+
+// SignOnSave makes every save of secrets update the vault index with dek;
+// see UpdateIndex.
+func SignOnSave(secrets *store.Store, dek []byte) {
+	secrets.OnSave(func(saved store.Secrets, changed []string) error {
+		return UpdateIndex(saved, changed, dek)
+	})
+}
+
+func (v *Vault) sign(entries map[string]string, dek []byte) error {
+	if len(dek) != keySize {
+		return errors.New("vault key has unexpected length")
+	}
+	index := &Index{Signed: time.Now().UTC(), Entries: entries}
+	index.MAC = hex.EncodeToString(index.mac(dek))
+
+	v.Index = index
+	if err := v.Save(); err != nil {
+		return fmt.Errorf("error signing index: %w", err)
+	}
+	return nil
+}
+
+// CheckIndex compares secrets with the index signed under dek.
+func (v *Vault) CheckIndex(secrets store.Secrets, dek []byte) (*IndexReport, error) {
+	if len(dek) != keySize {
+		return nil, errors.New("vault key has unexpected length")
+	}
+
+	report := &IndexReport{}
+	if v.Index == nil {
+		report.Unsigned = true
+		return report, nil
+	}
+	if !v.Index.valid(dek) {
+		report.Forged = true
+		return report, nil
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(v.Index.Entries)) {
+		entry, ok := secrets[name]
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, name)
+		case digest(entry.Value) != v.Index.Entries[name]:
+			report.Changed = append(report.Changed, name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(secrets)) {
+		if _, ok := v.Index.Entries[name]; !ok {
+			report.Added = append(report.Added, name)
+		}
+	}
+	return report, nil
+}
+
+// valid reports whether the index carries a correct MAC under dek.
+func (ix *Index) valid(dek []byte) bool {
+	mac, err := hex.DecodeString(ix.MAC)
+	return err == nil && hmac.Equal(mac, ix.mac(dek))
+}
+
+// mac authenticates the signing time and the sorted entries with a key
+// derived from dek, so the index never reuses the DEK directly.
+func (ix *Index) mac(dek []byte) []byte {
+	kdf := hmac.New(sha256.New, dek)
+	kdf.Write([]byte("deecli-index-v1"))
+
+	h := hmac.New(sha256.New, kdf.Sum(nil))
+	writeField(h, []byte(ix.Signed.UTC().Format(time.RFC3339Nano)))
+	for _, name := range slices.Sorted(maps.Keys(ix.Entries)) {
+		writeField(h, []byte(name))
+		writeField(h, []byte(ix.Entries[name]))
+	}
+	return h.Sum(nil)
+}
+
+// writeField writes b length-prefixed, so adjacent fields cannot be
+// re-split.
+func writeField(h hash.Hash, b []byte) {
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(len(b)))
+	h.Write(n[:])
+	h.Write(b)
+}
+
+func digest(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
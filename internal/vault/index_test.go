@@ -0,0 +1,145 @@
+package vault
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/deeragoo/deecli/internal/store"
+)
+
+func signedVault(t *testing.T, secrets store.Secrets) []byte {
+	t.Helper()
+	useMemoryStore(t)
+	dek, err := Init("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SignIndex(secrets, dek); err != nil {
+		t.Fatal(err)
+	}
+	return dek
+}
+
+func TestCheckIndex(t *testing.T) {
+	signed := store.Secrets{"aws": {Value: "a1"}, "github": {Value: "g1"}}
+
+	tests := []struct {
+		name    string
+		secrets store.Secrets
+		tamper  func(*Vault)
+		want    IndexReport
+	}{
+		{
+			name:    "unchanged",
+			secrets: store.Secrets{"aws": {Value: "a1"}, "github": {Value: "g1"}},
+		},
+		{
+			name:    "deleted, added and replaced",
+			secrets: store.Secrets{"github": {Value: "g2"}, "stripe": {Value: "s1"}},
+			want:    IndexReport{Missing: []string{"aws"}, Added: []string{"stripe"}, Changed: []string{"github"}},
+		},
+		{
+			name:    "index edited",
+			secrets: store.Secrets{"aws": {Value: "a1"}},
+			tamper:  func(v *Vault) { delete(v.Index.Entries, "github") },
+			want:    IndexReport{Forged: true},
+		},
+		{
+			name:    "no index",
+			secrets: store.Secrets{"aws": {Value: "a1"}},
+			tamper:  func(v *Vault) { v.Index = nil },
+			want:    IndexReport{Unsigned: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dek := signedVault(t, signed)
+			v, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.tamper != nil {
+				tt.tamper(v)
+			}
+			report, err := v.CheckIndex(tt.secrets, dek)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*report, tt.want) {
+				t.Errorf("CheckIndex = %+v, want %+v", *report, tt.want)
+			}
+			if report.OK() != reflect.DeepEqual(tt.want, IndexReport{}) {
+				t.Errorf("OK() = %t for %+v", report.OK(), *report)
+			}
+		})
+	}
+}
+
+func TestCheckIndexWrongKey(t *testing.T) {
+	signedVault(t, store.Secrets{"aws": {Value: "a1"}})
+	v, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := v.CheckIndex(store.Secrets{"aws": {Value: "a1"}}, make([]byte, keySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Forged {
+		t.Error("index verified under a different key")
+	}
+}
+
+// A save through deecli must not sign tampering with entries it did not
+// change.
+func TestSaveKeepsReportingTampering(t *testing.T) {
+	dek := signedVault(t, store.Secrets{"aws": {Value: "a1"}, "github": {Value: "g1"}, "old": {Value: "o1"}})
+	b := store.DefaultBackend().(*store.MemoryBackend)
+	if err := b.Save(store.Secrets{"aws": {Value: "a1"}, "github": {Value: "replayed"}, "old": {Value: "o1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := store.Open(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SignOnSave(s, dek)
+	s.Put("stripe", "s1")
+	s.Delete("old")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := b.Load()
+	report, err := v.CheckIndex(saved, dek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := IndexReport{Changed: []string{"github"}}
+	if !reflect.DeepEqual(*report, want) {
+		t.Errorf("CheckIndex after an unrelated save = %+v, want %+v", *report, want)
+	}
+}
+
+func TestUpdateIndexForged(t *testing.T) {
+	dek := signedVault(t, store.Secrets{"aws": {Value: "a1"}})
+	v, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Index.Entries["aws"] = digest("forged")
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = UpdateIndex(store.Secrets{"aws": {Value: "forged"}, "github": {Value: "g1"}}, []string{"github"}, dek)
+	if !errors.Is(err, ErrIndexForged) {
+		t.Fatalf("UpdateIndex over a forged index returned %v, want ErrIndexForged", err)
+	}
+}
@@ -0,0 +1,158 @@
+// Package vault implements the optional master-passphrase mode for
+// ~/.secrets.json: a random data-encryption key (DEK) is wrapped once by the
+// master passphrase and stored in ~/.secrets.vault.json, and entries are
+// sealed directly under the DEK.
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/deeragoo/deecli/internal/envelope"
+	"github.com/deeragoo/deecli/internal/store"
+)
+
+// FormatVersion is the vault file version written by Save.
+const FormatVersion = 1
+
+const keySize = 32
+
+// ErrNotInitialized is returned by Load when no vault file exists.
+var ErrNotInitialized = errors.New("vault not initialized (run 'deecli vault init')")
+
+// ErrIncorrectPassphrase is returned by Unlock when the passphrase does not
+// open the vault key.
+var ErrIncorrectPassphrase = errors.New("incorrect vault passphrase")
+
+// Vault is the on-disk vault header.
+type Vault struct {
+	Version int `json:"version"`
+
+	// Key is the DEK sealed in an envelope under the master passphrase.
+	Key string `json:"key"`
+
+	// Index authenticates the names and values in ~/.secrets.json.
+	Index *Index `json:"index,omitempty"`
+}
+
+// Path returns the location of the vault file.
+func Path() string {
+	return store.SidecarPath("vault")
+}
+
+// Exists reports whether a vault has been initialized.
+func Exists() bool {
+	_, err := os.Stat(Path())
+	return err == nil
+}
+
+// Load reads the vault header from disk.
+func Load() (*Vault, error) {
+	data, err := os.ReadFile(Path())
+	if os.IsNotExist(err) {
+		return nil, ErrNotInitialized
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading vault file: %w", err)
+	}
+
+	v := &Vault{}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, fmt.Errorf("error decoding vault file: %w", err)
+	}
+	if v.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported vault version %d", v.Version)
+	}
+	return v, nil
+}
+
+// Init creates a new vault with a fresh DEK wrapped by passphrase and
+// returns the DEK. It refuses to replace an existing vault.
+func Init(passphrase string) ([]byte, error) {
+	if Exists() {
+		return nil, errors.New("vault already initialized")
+	}
+
+	dek := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return nil, err
+	}
+
+	v := &Vault{Version: FormatVersion}
+	if err := v.wrap(dek, passphrase); err != nil {
+		return nil, err
+	}
+	if err := v.Save(); err != nil {
+		return nil, err
+	}
+	return dek, nil
+}
+
+// Restore writes a vault header wrapping dek, a key recovered from shares,
+// under a new passphrase. It replaces any existing vault header, keeping its
+// index.
+func Restore(dek []byte, passphrase string) error {
+	if len(dek) != keySize {
+		return errors.New("vault key has unexpected length")
+	}
+
+	v := &Vault{Version: FormatVersion}
+	if existing, err := Load(); err == nil {
+		v.Index = existing.Index
+	}
+	if err := v.wrap(dek, passphrase); err != nil {
+		return err
+	}
+	return v.Save()
+}
+
+// Unlock unwraps the DEK with the master passphrase.
+func (v *Vault) Unlock(passphrase string) ([]byte, error) {
+	dek, err := envelope.Open(v.Key, passphrase)
+	if errors.Is(err, envelope.ErrAuthFailed) {
+		return nil, ErrIncorrectPassphrase
+	} else if err != nil {
+		return nil, fmt.Errorf("invalid vault key: %w", err)
+	}
+	if len(dek) != keySize {
+		return nil, errors.New("vault key has unexpected length")
+	}
+	return dek, nil
+}
+
+// ChangePassphrase rewraps the DEK under newPassphrase. Entries are not
+// touched since they remain sealed under the same DEK.
+func (v *Vault) ChangePassphrase(oldPassphrase, newPassphrase string) error {
+	dek, err := v.Unlock(oldPassphrase)
+	if err != nil {
+		return err
+	}
+	if err := v.wrap(dek, newPassphrase); err != nil {
+		return err
+	}
+	return v.Save()
+}
+
+// Save atomically writes the vault header with owner-only permissions.
+func (v *Vault) Save() error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON marshal error: %w", err)
+	}
+	if err := store.WriteFile(Path(), data); err != nil {
+		return fmt.Errorf("error writing vault file: %w", err)
+	}
+	return nil
+}
+
+func (v *Vault) wrap(dek []byte, passphrase string) error {
+	wrapped, err := envelope.Seal(dek, passphrase, envelope.DefaultParams)
+	if err != nil {
+		return fmt.Errorf("error wrapping vault key: %w", err)
+	}
+	v.Key = wrapped
+	return nil
+}
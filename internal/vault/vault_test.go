@@ -0,0 +1,93 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/deeragoo/deecli/internal/store"
+)
+
+// useMemoryStore points the store, and with it the vault file, at a fresh
+// MemoryBackend for the duration of the test.
+func useMemoryStore(t *testing.T) *store.MemoryBackend {
+	t.Helper()
+	b := store.NewMemoryBackend(nil)
+	store.SetDefaultBackend(b)
+	t.Cleanup(func() {
+		os.RemoveAll(filepath.Dir(Path()))
+		store.SetDefaultBackend(nil)
+	})
+	return b
+}
+
+func TestInitUnlock(t *testing.T) {
+	useMemoryStore(t)
+
+	if Exists() {
+		t.Fatal("vault exists before Init")
+	}
+	dek, err := Init("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Init("again"); err == nil {
+		t.Error("second Init replaced the vault")
+	}
+
+	v, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		passphrase string
+		wantErr    error
+	}{
+		{"correct passphrase", "correct horse", nil},
+		{"wrong passphrase", "wrong horse", ErrIncorrectPassphrase},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Unlock(tt.passphrase)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Unlock returned %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(got, dek) {
+				t.Error("Unlock returned a different key than Init")
+			}
+		})
+	}
+}
+
+func TestChangePassphrase(t *testing.T) {
+	useMemoryStore(t)
+	dek, err := Init("old passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.ChangePassphrase("wrong", "new passphrase"); !errors.Is(err, ErrIncorrectPassphrase) {
+		t.Fatalf("ChangePassphrase with a wrong passphrase returned %v", err)
+	}
+	if err := v.ChangePassphrase("old passphrase", "new passphrase"); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Unlock("old passphrase"); !errors.Is(err, ErrIncorrectPassphrase) {
+		t.Errorf("old passphrase still unlocks: %v", err)
+	}
+	got, err := v.Unlock("new passphrase")
+	if err != nil || !bytes.Equal(got, dek) {
+		t.Errorf("new passphrase unlocks %x, %v; want the original key", got, err)
+	}
+}